@@ -1,76 +1,549 @@
 package graph
 
+import (
+	"container/heap"
+	"fmt"
+	gosort "sort"
+)
+
 // visit is a user defined function that is passed the vertex id
 type visit func(int) error
 
-// sort orders the vertices in order of dependencies
+// sort orders the vertices in order of dependencies, ties are broken by vertex index
 func sort(g *graph) []int {
-	// count neighbours that point to a given vertex
-	neighbours := make(map[int]int, g.vertices())
+	return sortBy(g, func(v, w int) bool { return v < w })
+}
+
+// sortBy orders the vertices in order of dependencies, among the vertices whose
+// parents are all sorted the least one according to less comes first. A nil less
+// keeps vertices in the order they became ready. Vertices that are part of a cycle
+// are left out. It runs in linear time when less is nil, and in O(V log V + E) time
+// otherwise as Kahn's algorithm then keeps ready vertices in a priority queue.
+func sortBy(g *graph, less func(v, w int) bool) []int {
+	// count parents of every vertex
+	indegree := make([]int, g.vertices())
+	for v := 0; v < g.vertices(); v++ {
+		for _, w := range g.adjascent(v) {
+			indegree[w]++
+		}
+	}
 
+	roots := []int{}
 	for v := 0; v < g.vertices(); v++ {
-		neighbours[v] = 0
-		for w := 0; w < g.vertices(); w++ {
-			if w == v {
+		if indegree[v] == 0 {
+			roots = append(roots, v)
+		}
+	}
+	ready := newVertexQueue(roots, less)
+
+	sorted := make([]int, 0, g.vertices())
+
+	for ready.Len() > 0 {
+		v := ready.pop()
+		sorted = append(sorted, v)
+
+		for _, w := range g.adjascent(v) {
+			indegree[w]--
+			if indegree[w] == 0 {
+				ready.push(w)
+			}
+		}
+	}
+
+	return sorted
+}
+
+// vertexQueue holds ready vertices, it is a priority queue ordered by less built on
+// container/heap, or a FIFO queue when less is nil
+type vertexQueue struct {
+	vertices []int
+	less     func(v, w int) bool
+	// head is the first vertex of a FIFO queue
+	head int
+}
+
+func newVertexQueue(vertices []int, less func(v, w int) bool) *vertexQueue {
+	q := &vertexQueue{vertices: vertices, less: less}
+	if less != nil {
+		heap.Init(q)
+	}
+	return q
+}
+
+func (q *vertexQueue) Len() int           { return len(q.vertices) - q.head }
+func (q *vertexQueue) Less(i, j int) bool { return q.less(q.vertices[i], q.vertices[j]) }
+func (q *vertexQueue) Swap(i, j int)      { q.vertices[i], q.vertices[j] = q.vertices[j], q.vertices[i] }
+func (q *vertexQueue) Push(x interface{}) { q.vertices = append(q.vertices, x.(int)) }
+
+func (q *vertexQueue) Pop() interface{} {
+	v := q.vertices[len(q.vertices)-1]
+	q.vertices = q.vertices[:len(q.vertices)-1]
+	return v
+}
+
+func (q *vertexQueue) push(v int) {
+	if q.less == nil {
+		q.vertices = append(q.vertices, v)
+		return
+	}
+	heap.Push(q, v)
+}
+
+func (q *vertexQueue) pop() int {
+	if q.less == nil {
+		q.head++
+		return q.vertices[q.head-1]
+	}
+	return heap.Pop(q).(int)
+}
+
+// cycles finds the cycles closed by back edges during a depth first search.
+// Each cycle lists vertices in edge order, starting from the vertex the back
+// edge points to.
+func cycles(g *graph) [][]int {
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+
+	state := make([]int, g.vertices())
+	stack := []int{}
+	found := [][]int{}
+
+	var visitInner func(v int)
+
+	visitInner = func(v int) {
+		state[v] = onStack
+		stack = append(stack, v)
+		for _, w := range g.adjascent(v) {
+			switch state[w] {
+			case unvisited:
+				visitInner(w)
+			case onStack:
+				// back edge v -> w closes the cycle w ... v
+				i := len(stack) - 1
+				for stack[i] != w {
+					i--
+				}
+				found = append(found, append([]int{}, stack[i:]...))
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[v] = done
+	}
+
+	for v := 0; v < g.vertices(); v++ {
+		if state[v] == unvisited {
+			visitInner(v)
+		}
+	}
+
+	return found
+}
+
+// allCycles enumerates every elementary cycle, see elementaryCycles. The depth
+// first search of cycles keeps graphs without cycles linear.
+func allCycles(g *graph) [][]int {
+	if len(cycles(g)) == 0 {
+		return nil
+	}
+	return elementaryCycles(g)
+}
+
+// reachable marks the vertices that can be reached from any of the sources,
+// the sources themselves are marked
+func reachable(g *graph, sources ...int) []bool {
+	marked := make([]bool, g.vertices())
+	stack := append([]int{}, sources...)
+
+	for len(stack) > 0 {
+		v := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if marked[v] {
+			continue
+		}
+		marked[v] = true
+		stack = append(stack, g.adjascent(v)...)
+	}
+
+	return marked
+}
+
+// components finds the strongly connected components of the subgraph of vertices
+// numbered min or more, using Tarjan's algorithm. Components are returned in
+// reverse topological order, with their vertices in ascending order.
+func components(g *graph, min int) [][]int {
+	index := make([]int, g.vertices())
+	lowlink := make([]int, g.vertices())
+	onStack := make([]bool, g.vertices())
+	stack := []int{}
+	next := 1
+	found := [][]int{}
+
+	var connect func(v int)
+
+	connect = func(v int) {
+		index[v] = next
+		lowlink[v] = next
+		next++
+		stack = append(stack, v)
+		onStack[v] = true
+
+		for _, w := range g.adjascent(v) {
+			if w < min {
 				continue
 			}
-			for _, t := range g.adjascent(w) {
-				if t == v {
-					neighbours[v]++
+			if index[w] == 0 {
+				connect(w)
+				if lowlink[w] < lowlink[v] {
+					lowlink[v] = lowlink[w]
 				}
+			} else if onStack[w] && index[w] < lowlink[v] {
+				lowlink[v] = index[w]
 			}
 		}
+
+		// v is the root of a component
+		if lowlink[v] == index[v] {
+			component := []int{}
+			for {
+				w := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[w] = false
+				component = append(component, w)
+				if w == v {
+					break
+				}
+			}
+			gosort.Ints(component)
+			found = append(found, component)
+		}
 	}
 
-	// initialize an empty list of sorted vertices
-	sorted := []int{}
+	for v := min; v < g.vertices(); v++ {
+		if index[v] == 0 {
+			connect(v)
+		}
+	}
 
-	for len(neighbours) != 0 {
-		toRemove := -1
-		for v, n := range neighbours {
-			if n == 0 {
-				toRemove = v
+	return found
+}
+
+// cyclic reports whether a strongly connected component contains a cycle
+func cyclic(g *graph, component []int) bool {
+	if len(component) > 1 {
+		return true
+	}
+	for _, w := range g.adjascent(component[0]) {
+		if w == component[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// elementaryCycles enumerates every elementary cycle using Johnson's algorithm.
+// Each cycle lists vertices in edge order, starting from its lowest vertex. The
+// number of cycles may grow exponentially with the size of the graph.
+func elementaryCycles(g *graph) [][]int {
+	found := [][]int{}
+	blocked := make([]bool, g.vertices())
+	blockedBy := make([]map[int]bool, g.vertices())
+	inComponent := make([]bool, g.vertices())
+	stack := []int{}
+
+	var unblock func(v int)
+
+	unblock = func(v int) {
+		blocked[v] = false
+		for w := range blockedBy[v] {
+			delete(blockedBy[v], w)
+			if blocked[w] {
+				unblock(w)
+			}
+		}
+	}
+
+	var circuit func(start, v int) bool
+
+	circuit = func(start, v int) bool {
+		closed := false
+		stack = append(stack, v)
+		blocked[v] = true
+
+		for _, w := range g.adjascent(v) {
+			if !inComponent[w] {
+				continue
+			}
+			if w == start {
+				found = append(found, append([]int{}, stack...))
+				closed = true
+			} else if !blocked[w] && circuit(start, w) {
+				closed = true
+			}
+		}
+
+		if closed {
+			unblock(v)
+		} else {
+			for _, w := range g.adjascent(v) {
+				if inComponent[w] {
+					blockedBy[w][v] = true
+				}
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		return closed
+	}
+
+	for start := 0; start < g.vertices(); start++ {
+		// cycles through start only use vertices of its component in the
+		// subgraph of vertices numbered start or more
+		var component []int
+		for _, c := range components(g, start) {
+			if c[0] == start {
+				component = c
 				break
 			}
 		}
+		if !cyclic(g, component) {
+			continue
+		}
 
-		for _, w := range g.adjascent(toRemove) {
-			neighbours[w]--
+		for v := range inComponent {
+			inComponent[v] = false
+		}
+		for _, v := range component {
+			inComponent[v] = true
+			blocked[v] = false
+			blockedBy[v] = map[int]bool{}
 		}
 
-		delete(neighbours, toRemove)
-		sorted = append(sorted, toRemove)
+		circuit(start, start)
 	}
 
-	return sorted
+	return found
 }
 
-// dfs visits all nodes in the graph
-func dfs(g *graph, visitor visit) {
+// breakingEdges suggests a small set of edges, covering every given cycle, whose
+// removal leaves the graph acyclic. The edge contained in most remaining cycles
+// is picked first, as finding the minimum set is NP-hard.
+func breakingEdges(cycles [][]int) [][2]int {
+	edgesOf := func(cycle []int) [][2]int {
+		edges := make([][2]int, len(cycle))
+		for i, v := range cycle {
+			edges[i] = [2]int{v, cycle[(i+1)%len(cycle)]}
+		}
+		return edges
+	}
+
+	remaining := map[int]bool{}
+	for i := range cycles {
+		remaining[i] = true
+	}
+
+	breaks := [][2]int{}
+	for len(remaining) > 0 {
+		counts := map[[2]int]int{}
+		for i := range remaining {
+			for _, e := range edgesOf(cycles[i]) {
+				counts[e]++
+			}
+		}
+
+		var best [2]int
+		bestCount := 0
+		for e, n := range counts {
+			if n > bestCount || n == bestCount && (e[0] < best[0] || e[0] == best[0] && e[1] < best[1]) {
+				best, bestCount = e, n
+			}
+		}
+
+		breaks = append(breaks, best)
+		for i := range remaining {
+			for _, e := range edgesOf(cycles[i]) {
+				if e == best {
+					delete(remaining, i)
+					break
+				}
+			}
+		}
+	}
+
+	return breaks
+}
+
+// dfs visits all nodes in the graph, a vertex is visited after the vertices
+// reachable from it. The walk stops at the first error returned by visitor.
+func dfs(g *graph, visitor visit) error {
 	visited := make([]bool, g.vertices())
 
 	var dfsInner func(w int) error
 
 	dfsInner = func(w int) error {
-		var err error
-		for _, w := range g.adjascent(w) {
-			err = dfsInner(w)
-			if err != nil {
-				break
+		visited[w] = true
+		for _, x := range g.adjascent(w) {
+			if visited[x] {
+				continue
 			}
+			if err := dfsInner(x); err != nil {
+				return err
+			}
+		}
+		return visitor(w)
+	}
+
+	for v := 0; v < g.vertices(); v++ {
+		if visited[v] {
+			continue
 		}
-		if err != nil {
+		if err := dfsInner(v); err != nil {
 			return err
 		}
-		if visited[w] {
-			return nil
+	}
+
+	return nil
+}
+
+// bfs visits the vertices reachable from start in breadth first order. The walk
+// stops at the first error returned by visitor.
+func bfs(g *graph, start int, visitor visit) error {
+	visited := make([]bool, g.vertices())
+	visited[start] = true
+	queue := []int{start}
+
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+
+		if err := visitor(v); err != nil {
+			return err
+		}
+
+		for _, w := range g.adjascent(v) {
+			if !visited[w] {
+				visited[w] = true
+				queue = append(queue, w)
+			}
 		}
-		err = visitor(w)
-		visited[w] = true
-		return err
 	}
 
-	for v := 0; v < g.vertices(); v++ {
-		dfsInner(v)
+	return nil
+}
+
+// levels groups sorted vertices into waves, each vertex placed one wave after
+// the latest of its parents. Vertices within a wave keep their sorted order.
+func levels(g *graph, sorted []int) [][]int {
+	level := make([]int, g.vertices())
+	waves := [][]int{}
+
+	for _, v := range sorted {
+		if level[v] == len(waves) {
+			waves = append(waves, []int{})
+		}
+		waves[level[v]] = append(waves[level[v]], v)
+
+		for _, w := range g.adjascent(v) {
+			if level[w] < level[v]+1 {
+				level[w] = level[v] + 1
+			}
+		}
+	}
+
+	return waves
+}
+
+// acyclic returns a CycleError naming vertices with fmt.Sprint, if the graph has cycles
+func (gr *Graph[K]) acyclic() error {
+	return gr.cycleError(func(k K) string { return fmt.Sprint(k) })
+}
+
+// cycleError returns a CycleError listing every elementary cycle of the graph,
+// or nil when it has none
+func (gr *Graph[K]) cycleError(name func(K) string) error {
+	found := allCycles(gr.g)
+	if len(found) == 0 {
+		return nil
+	}
+
+	ce := &CycleError{}
+	for _, cycle := range found {
+		names := make([]string, len(cycle))
+		for i, v := range cycle {
+			names[i] = name(gr.keys[v])
+		}
+		ce.Cycles = append(ce.Cycles, names)
+	}
+	return ce
+}
+
+// TopologicalSort orders vertices so that every vertex comes before the vertices it
+// has edges to. A CycleError is returned if the graph has cycles.
+func (gr *Graph[K]) TopologicalSort() ([]K, error) {
+	if err := gr.acyclic(); err != nil {
+		return nil, err
+	}
+	return gr.keysOf(sort(gr.g)), nil
+}
+
+// DFS visits every vertex depth first, a vertex is visited after all vertices
+// reachable from it. The walk stops at the first error returned by visit.
+func (gr *Graph[K]) DFS(visit func(K) error) error {
+	return dfs(gr.g, func(v int) error {
+		return visit(gr.keys[v])
+	})
+}
+
+// BFS visits the vertices reachable from start, including start, in breadth first
+// order. The walk stops at the first error returned by visit.
+func (gr *Graph[K]) BFS(start K, visit func(K) error) error {
+	v, found := gr.index[start]
+	if !found {
+		return nil
 	}
+	return bfs(gr.g, v, func(w int) error {
+		return visit(gr.keys[w])
+	})
+}
+
+// Reachable reports whether there is a path from -> to, a vertex is reachable from itself
+func (gr *Graph[K]) Reachable(from, to K) bool {
+	v, vFound := gr.index[from]
+	w, wFound := gr.index[to]
+	if !vFound || !wFound {
+		return false
+	}
+	return reachable(gr.g, v)[w]
+}
+
+// TransitiveReduction returns a copy of the graph without the edges implied by
+// longer paths. A CycleError is returned if the graph has cycles.
+func (gr *Graph[K]) TransitiveReduction() (*Graph[K], error) {
+	if err := gr.acyclic(); err != nil {
+		return nil, err
+	}
+
+	reduced := NewGraph[K]()
+	for _, k := range gr.keys {
+		reduced.AddVertex(k)
+	}
+
+	for v := range gr.keys {
+		// vertices reachable from v through at least two edges
+		indirect := []int{}
+		for _, w := range gr.g.adjascent(v) {
+			indirect = append(indirect, gr.g.adjascent(w)...)
+		}
+		implied := reachable(gr.g, indirect...)
+
+		for _, w := range gr.g.adjascent(v) {
+			if !implied[w] {
+				reduced.AddEdge(gr.keys[v], gr.keys[w])
+			}
+		}
+	}
+
+	return reduced, nil
 }
@@ -1,7 +1,9 @@
 package graph
 
 import (
+	"context"
 	"reflect"
+	"strconv"
 	"testing"
 )
 
@@ -101,3 +103,255 @@ func TestDFS(t *testing.T) {
 		t.Fatal("dfs did not work")
 	}
 }
+
+func TestCycles(t *testing.T) {
+	g := newGraph(4)
+	g.addEdge(0, 1)
+	g.addEdge(1, 2)
+	g.addEdge(2, 0)
+	g.addEdge(3, 3)
+
+	found := cycles(g)
+
+	t.Logf("cycles=%v", found)
+
+	if !reflect.DeepEqual(found, [][]int{{0, 1, 2}, {3}}) {
+		t.Fatalf("unexpected cycles %v", found)
+	}
+
+	dag := newGraph(3)
+	dag.addEdge(0, 1)
+	dag.addEdge(0, 2)
+	dag.addEdge(1, 2)
+
+	if found := cycles(dag); len(found) != 0 {
+		t.Fatalf("expected no cycles in a dag, got %v", found)
+	}
+}
+
+func TestSortByReadiness(t *testing.T) {
+	g := newGraph(4)
+	g.addEdge(0, 3)
+	g.addEdge(1, 2)
+
+	if sorted := sortBy(g, nil); !reflect.DeepEqual(sorted, []int{0, 1, 3, 2}) {
+		t.Fatalf("expected vertices in the order they became ready, sorted = %v", sorted)
+	}
+}
+
+func TestSortCycle(t *testing.T) {
+	g := newGraph(3)
+	g.addEdge(0, 1)
+	g.addEdge(1, 2)
+	g.addEdge(2, 1)
+
+	sorted := sort(g)
+
+	if len(sorted) != 1 || sorted[0] != 0 {
+		t.Fatalf("expected only 0 to be sorted, sorted = %v", sorted)
+	}
+}
+
+func TestLevels(t *testing.T) {
+	g := newGraph(5)
+	g.addEdge(0, 1)
+	g.addEdge(1, 2)
+	g.addEdge(0, 2)
+	g.addEdge(3, 2)
+
+	waves := levels(g, []int{0, 3, 4, 1, 2})
+
+	if !reflect.DeepEqual(waves, [][]int{{0, 3, 4}, {1}, {2}}) {
+		t.Fatalf("unexpected waves %v", waves)
+	}
+}
+
+func TestGraphAlgorithms(t *testing.T) {
+	g := NewGraph[string]()
+	g.AddEdge("a", "b")
+	g.AddEdge("b", "c")
+	g.AddEdge("a", "c")
+	g.AddEdge("c", "d")
+	g.AddEdge("a", "d")
+	g.AddVertex("e")
+
+	sorted, err := g.TopologicalSort()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	position := map[string]int{}
+	for i, k := range sorted {
+		position[k] = i
+	}
+	if len(sorted) != 5 || position["a"] > position["b"] || position["b"] > position["c"] || position["c"] > position["d"] {
+		t.Fatalf("unexpected order %v", sorted)
+	}
+
+	visited := []string{}
+	g.BFS("a", func(k string) error {
+		visited = append(visited, k)
+		return nil
+	})
+	if !reflect.DeepEqual(visited, []string{"a", "b", "c", "d"}) {
+		t.Fatalf("unexpected bfs order %v", visited)
+	}
+
+	visited = []string{}
+	g.DFS(func(k string) error {
+		visited = append(visited, k)
+		return nil
+	})
+	if !reflect.DeepEqual(visited, []string{"d", "c", "b", "a", "e"}) {
+		t.Fatalf("unexpected dfs order %v", visited)
+	}
+
+	if !g.Reachable("a", "d") || g.Reachable("d", "a") || g.Reachable("a", "e") {
+		t.Fatal("unexpected reachability")
+	}
+
+	reduced, err := g.TransitiveReduction()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if reduced.HasEdge("a", "c") || reduced.HasEdge("a", "d") || !reduced.HasEdge("a", "b") || !reduced.HasEdge("c", "d") {
+		t.Fatalf("unexpected reduction:\n%v", reduced)
+	}
+
+	g.AddEdge("d", "a")
+	if _, err := g.TopologicalSort(); err == nil {
+		t.Fatal("expected a CycleError")
+	}
+}
+
+// benchmarkVertices is the size of the graphs used by benchmarks
+const benchmarkVertices = 10000
+
+// layeredGraph links every vertex to the next one and to a vertex twice its index
+func layeredGraph(v int) *graph {
+	g := newGraph(v)
+	for w := 1; w < v; w++ {
+		g.addEdge(w-1, w)
+		if 2*w < v {
+			g.addEdge(w, 2*w)
+		}
+	}
+	return g
+}
+
+// fanGraph has a single vertex every other vertex depends on
+func fanGraph(v int) *graph {
+	g := newGraph(v)
+	for w := 1; w < v; w++ {
+		g.addEdge(0, w)
+	}
+	return g
+}
+
+func benchmarkResources(v int) []Resource {
+	update := func(x interface{}) (string, error) { return "", nil }
+	del := func(x interface{}) error { return nil }
+
+	resources := make([]Resource, v)
+	for i := range resources {
+		deps := []Dependency{}
+		if i > 0 {
+			deps = append(deps, Dependency{FromResource: "r0"}, Dependency{FromResource: "r" + strconv.Itoa(i/2)})
+		}
+		resources[i] = MakeResource("r"+strconv.Itoa(i), deps, &dynamo{}, update, del)
+	}
+	return resources
+}
+
+func BenchmarkSortLayered10k(b *testing.B) {
+	g := layeredGraph(benchmarkVertices)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sort(g)
+	}
+}
+
+func BenchmarkSortFan10k(b *testing.B) {
+	g := fanGraph(benchmarkVertices)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sort(g)
+	}
+}
+
+func BenchmarkCycles10k(b *testing.B) {
+	g := layeredGraph(benchmarkVertices)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		cycles(g)
+	}
+}
+
+func BenchmarkBuildGraph10k(b *testing.B) {
+	resources := benchmarkResources(benchmarkVertices)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buildGraph(resources)
+	}
+}
+
+// benchmarkSync syncs v resources, comparing sizes shows whether Sync is linear
+func benchmarkSync(b *testing.B, v int) {
+	ctxt := context.Background()
+	resources := benchmarkResources(v)
+	lib := New(nil)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := lib.Sync(ctxt, resources, false); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSync10k(b *testing.B) { benchmarkSync(b, benchmarkVertices) }
+
+func BenchmarkSync40k(b *testing.B) { benchmarkSync(b, 4*benchmarkVertices) }
+
+func TestComponents(t *testing.T) {
+	g := newGraph(6)
+	g.addEdge(0, 1)
+	g.addEdge(1, 2)
+	g.addEdge(2, 0)
+	g.addEdge(2, 3)
+	g.addEdge(3, 4)
+	g.addEdge(4, 3)
+	g.addEdge(5, 5)
+
+	found := components(g, 0)
+
+	if !reflect.DeepEqual(found, [][]int{{3, 4}, {0, 1, 2}, {5}}) {
+		t.Fatalf("unexpected components %v", found)
+	}
+
+	if !reflect.DeepEqual(components(g, 1), [][]int{{3, 4}, {2}, {1}, {5}}) {
+		t.Fatalf("unexpected components of the subgraph %v", components(g, 1))
+	}
+}
+
+func TestElementaryCycles(t *testing.T) {
+	g := newGraph(4)
+	g.addEdge(0, 1)
+	g.addEdge(1, 2)
+	g.addEdge(2, 0)
+	g.addEdge(1, 0)
+	g.addEdge(2, 3)
+	g.addEdge(3, 1)
+	g.addEdge(3, 3)
+
+	found := elementaryCycles(g)
+
+	t.Logf("cycles = %v", found)
+
+	if !reflect.DeepEqual(found, [][]int{{0, 1, 2}, {0, 1}, {1, 2, 3}, {3}}) {
+		t.Fatalf("unexpected cycles %v", found)
+	}
+
+	breaks := breakingEdges(found)
+	if !reflect.DeepEqual(breaks, [][2]int{{0, 1}, {1, 2}, {3, 3}}) {
+		t.Fatalf("unexpected breaks %v", breaks)
+	}
+}
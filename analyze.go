@@ -0,0 +1,67 @@
+package graph
+
+// Analysis describes the cycles in the dependency graph of a Resource slice.
+// Resources are named in dependency order, as in CycleError.
+type Analysis struct {
+	// Components lists the strongly connected components that contain cycles,
+	// the resources of a component all depend on each other.
+	Components [][]string
+	// Cycles lists every elementary cycle.
+	Cycles [][]string
+	// Breaks suggests a small set of dependencies whose removal breaks every cycle.
+	Breaks []Break
+}
+
+// Break identifies a Dependency of a Resource that closes a cycle.
+type Break struct {
+	Resource   string
+	Dependency Dependency
+}
+
+// Analyze validates the Resource slice like Sync, and reports the cycles in its
+// dependencies. The returned Analysis is empty when the dependencies form a DAG.
+func (lib *Lib) Analyze(resources []Resource) (*Analysis, error) {
+	err := check(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	g := buildGraph(resources).g
+
+	lib.logger("starting analysis")
+
+	names := func(vertices []int) []string {
+		n := make([]string, len(vertices))
+		for i, v := range vertices {
+			n[i] = resources[v].ResourceName()
+		}
+		return n
+	}
+
+	analysis := &Analysis{}
+
+	for _, component := range components(g, 0) {
+		if cyclic(g, component) {
+			analysis.Components = append(analysis.Components, names(component))
+		}
+	}
+
+	found := elementaryCycles(g)
+	for _, cycle := range found {
+		analysis.Cycles = append(analysis.Cycles, names(cycle))
+	}
+
+	index := indexes(resources)
+	for _, edge := range breakingEdges(found) {
+		from, to := resources[edge[0]], resources[edge[1]]
+		for _, dep := range dependencies(to, index) {
+			for _, name := range parents(dep) {
+				if name == from.ResourceName() {
+					analysis.Breaks = append(analysis.Breaks, Break{to.ResourceName(), dep})
+				}
+			}
+		}
+	}
+
+	return analysis, nil
+}
@@ -0,0 +1,49 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyze(t *testing.T) {
+	ctxt := context.Background()
+
+	update := func(x interface{}) (string, error) { return "", nil }
+	del := func(x interface{}) error { return nil }
+
+	// mydep -> mykin is the only dependency shared by both cycles
+	kinesisResource := MakeResource("mykin", []Dependency{{FromResource: "mydep"}}, &kinesis{ctxt: ctxt}, update, del)
+	dynamoResource := MakeResource("mydyn", []Dependency{{FromResource: "mykin"}}, &dynamo{ctxt: ctxt}, update, del)
+	deploymentResource := MakeResource("mydep", []Dependency{{FromResource: "mydyn"}, {FromResource: "myapi"}}, &deployment{ctxt: ctxt}, update, del)
+	apiResource := MakeResource("myapi", []Dependency{{FromResource: "mykin"}}, &deployment{ctxt: ctxt}, update, del)
+	logResource := MakeResource("mylog", []Dependency{{FromResource: "myapi"}}, &dynamo{ctxt: ctxt}, update, del)
+
+	resources := []Resource{kinesisResource, dynamoResource, deploymentResource, apiResource, logResource}
+
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	analysis, err := lib.Analyze(resources)
+	if err != nil {
+		t.Fatalf("unable to analyze %v", err)
+	}
+
+	t.Logf("analysis = %+v", analysis)
+
+	if !reflect.DeepEqual(analysis.Components, [][]string{{"mykin", "mydyn", "mydep", "myapi"}}) {
+		t.Fatalf("unexpected components %v", analysis.Components)
+	}
+
+	if !reflect.DeepEqual(analysis.Cycles, [][]string{{"mykin", "mydyn", "mydep"}, {"mykin", "myapi", "mydep"}}) {
+		t.Fatalf("unexpected cycles %v", analysis.Cycles)
+	}
+
+	if !reflect.DeepEqual(analysis.Breaks, []Break{{"mykin", Dependency{FromResource: "mydep"}}}) {
+		t.Fatalf("unexpected breaks %v", analysis.Breaks)
+	}
+
+	analysis, err = lib.Analyze([]Resource{dynamoResource, logResource, apiResource, kinesisResource})
+	if err == nil {
+		t.Fatal("expected missing mydep to fail the analysis")
+	}
+}
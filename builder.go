@@ -2,10 +2,10 @@ package graph
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"reflect"
 	"sync"
+	"time"
 )
 
 type bag string
@@ -27,6 +27,69 @@ type Dependency struct {
 	FromField string
 	// ToField is a public field in the current Resource's implementing struct.
 	ToField string
+	// Expression is a text/template rendered into ToField in place of copying
+	// FromField. Its data maps resource names to their structs, for instance
+	// arn:aws:kinesis:{{.mykin.Region}}:{{.mykin.StreamName}}. The resources
+	// it reads are dependencies, FromResource may be left empty.
+	Expression string
+	// Kind tells how the dependency affects the Resource, it defaults to Hard.
+	Kind DependencyKind
+}
+
+// DependencyKind tells whether a Dependency injects values and whether it must be
+// satisfied
+type DependencyKind string
+
+// Kinds of Dependency
+const (
+	// Hard dependencies wait for FromResource and inject its fields, the empty
+	// kind is a hard dependency.
+	Hard DependencyKind = "hard"
+	// OrderOnly dependencies wait for FromResource without injecting any field.
+	OrderOnly DependencyKind = "order"
+	// Soft dependencies are hard dependencies when the resources they refer to
+	// are in the slice, and are ignored otherwise.
+	Soft DependencyKind = "soft"
+)
+
+// dependencies returns the declared dependencies of a Resource that apply given the
+// names of the resources in the slice, soft dependencies on missing resources are left out
+func dependencies[V any](r Resource, names map[string]V) []Dependency {
+	deps := declared(r)
+
+	for i, dep := range deps {
+		if dep.Kind == Soft && !present(dep, names) {
+			// copy on the first dependency left out
+			kept := append([]Dependency{}, deps[:i]...)
+			for _, dep := range deps[i+1:] {
+				if dep.Kind != Soft || present(dep, names) {
+					kept = append(kept, dep)
+				}
+			}
+			return kept
+		}
+	}
+
+	return deps
+}
+
+// present reports whether every resource a Dependency refers to is named
+func present[V any](dep Dependency, names map[string]V) bool {
+	for _, name := range parents(dep) {
+		if _, found := names[name]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// indexes maps resource names to their position in the slice
+func indexes(resources []Resource) map[string]int {
+	m := make(map[string]int, len(resources))
+	for i, r := range resources {
+		m[r.ResourceName()] = i
+	}
+	return m
 }
 
 // ResourceName convenience function
@@ -47,8 +110,10 @@ type Resource interface {
 }
 
 type builderOutput struct {
-	status string
+	detail Result
 	result error
+	// hash of the spec, recorded once Update succeeded
+	hash string
 }
 
 // Depender captures dependencies between resources
@@ -90,213 +155,414 @@ func MakeResource(name string, dependencies []Dependency, uDef interface{}, updF
 // Sync method uses the Resource slice to generate a DAG. The DAG is processed based on the value
 // of toDelete flag. Resources may be processed concurrently. Processed resources may return a status
 // string and or an error. The function collects these and aggregates them in respective maps keyed by
-// resource names.
+// resource names. The overhead of Sync is linear in V resources and E dependencies. Ordering ready
+// resources by Opts.Ordering or Prioritizer makes it O(V log V + E).
 func (lib *Lib) Sync(ctxt context.Context, resources []Resource, toDelete bool) (map[string]string, error) {
+	g, err := prepare(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := lib.sync(ctxt, resources, g, toDelete)
+	return statuses(results), err
+}
+
+// prepare validates resources and builds their DAG. The vertex of a resource is
+// its position in the slice, so the DAG is returned as the graph used by the
+// scheduler.
+func prepare(resources []Resource) (*graph, error) {
 	err := check(resources)
 	if err != nil {
 		return nil, err
 	}
 
-	g := buildGraph(resources)
+	dag := buildGraph(resources)
 
+	err = dag.cycleError(Resource.ResourceName)
+	if err != nil {
+		return nil, err
+	}
+
+	return dag.g, nil
+}
+
+// sync processes the resources of the DAG g
+func (lib *Lib) sync(ctxt context.Context, resources []Resource, g *graph, toDelete bool) (map[string]Result, error) {
 	lib.logger("starting sync")
 
 	if toDelete {
 		return nil, lib.deleteSync(ctxt, resources, g)
 	}
 
-	return lib.createSync(ctxt, resources, g)
+	if lib.skipUnchanged && lib.store == nil {
+		return nil, fmt.Errorf("SkipUnchanged requires a StateStore")
+	}
+
+	var previous map[string]Record
+	if lib.skipUnchanged {
+		var err error
+		previous, err = lib.store.Load(ctxt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return lib.createSync(ctxt, resources, g, previous)
 }
 
-// check that resources have correct dependencies
+// check that resources have correct dependencies, every problem found is
+// returned in a CheckError
 func check(resources []Resource) error {
 	cache := map[string]Resource{}
-
-	for _, r := range resources {
-		cache[r.ResourceName()] = r
+	owner := map[string]int{}
+	errs := []error{}
+
+	for i, r := range resources {
+		n := r.ResourceName()
+		if _, found := cache[n]; found {
+			errs = append(errs, fmt.Errorf("duplicate Resource name %s", n))
+			continue
+		}
+		cache[n] = r
+		owner[n] = i
 	}
 
-	for n, r := range cache {
+	for i, r := range resources {
+		n := r.ResourceName()
+		if owner[n] != i {
+			continue
+		}
+
 		if reflect.ValueOf(r).Kind() != reflect.Ptr {
-			return fmt.Errorf("expected %s Resource to be implemented with a pointer to struct", n)
+			errs = append(errs, fmt.Errorf("expected %s Resource to be implemented with a pointer to struct", n))
+			continue
 		}
 
 		if reflect.ValueOf(r).Elem().Kind() != reflect.Struct {
-			return fmt.Errorf("expected %s Resource to be implemented using a pointer to struct", n)
+			errs = append(errs, fmt.Errorf("expected %s Resource to be implemented using a pointer to struct", n))
+			continue
+		}
+
+		if _, err := tagDependencies(r); err != nil {
+			errs = append(errs, fmt.Errorf("in %s Resource %v", n, err))
 		}
 
 		// validate each dependency
-		for _, dep := range r.ResourceDependencies() {
-			if len(dep.ToField) == 0 && len(dep.FromField) > 0 || len(dep.ToField) > 0 && len(dep.FromField) == 0 {
-				return fmt.Errorf("Resource %s incorrect specification of dependency on %s, fix FromField, ToField", r.ResourceName(), dep.FromResource)
-			}
-			if err := checkField(r, dep.ToField); err != nil {
-				return err
-			}
-			if _, ok := cache[dep.FromResource]; !ok {
-				return fmt.Errorf("Dependent resource %s doesn't exist", dep.FromResource)
-			}
-			if err := checkField(cache[dep.FromResource], dep.FromField); err != nil {
-				return err
+		for _, dep := range dependencies(r, cache) {
+			if err := checkDependency(r, dep, cache); err != nil {
+				errs = append(errs, err)
 			}
 		}
 	}
 
+	if len(errs) > 0 {
+		return &CheckError{Errors: errs}
+	}
+
+	return nil
+}
+
+// checkDependency validates that dep refers to existing fields, and that the value
+// of its FromField can be injected in its ToField
+func checkDependency(r Resource, dep Dependency, cache map[string]Resource) error {
+	switch dep.Kind {
+	case "", Hard, Soft:
+	case OrderOnly:
+		if len(dep.FromField) > 0 || len(dep.ToField) > 0 || len(dep.Expression) > 0 {
+			return fmt.Errorf("Resource %s order only dependency on %s cannot inject fields", r.ResourceName(), dep.FromResource)
+		}
+	default:
+		return fmt.Errorf("Resource %s unknown kind %s of dependency on %s", r.ResourceName(), dep.Kind, dep.FromResource)
+	}
+
+	if len(dep.Expression) > 0 {
+		return checkExpression(r, dep, cache)
+	}
+	if len(dep.ToField) == 0 && len(dep.FromField) > 0 || len(dep.ToField) > 0 && len(dep.FromField) == 0 {
+		return fmt.Errorf("Resource %s incorrect specification of dependency on %s, fix FromField, ToField", r.ResourceName(), dep.FromResource)
+	}
+	if err := checkField(r, dep.ToField); err != nil {
+		return err
+	}
+	from, ok := cache[dep.FromResource]
+	if !ok {
+		return fmt.Errorf("Dependent resource %s doesn't exist", dep.FromResource)
+	}
+	if err := checkField(from, dep.FromField); err != nil {
+		return err
+	}
+	if len(dep.FromField) == 0 {
+		return nil
+	}
+
+	fromType, _ := pathType(backingType(from), dep.FromField)
+	toType, _ := pathType(backingType(r), dep.ToField)
+	if !convertible(fromType, toType) {
+		return fmt.Errorf("Resource %s cannot inject %s.%s of type %s into %s of type %s", r.ResourceName(), dep.FromResource, dep.FromField, fromType, dep.ToField, toType)
+	}
+
 	return nil
 }
 
+// backing returns the struct implementing a Resource, for resources created with
+// MakeResource it is the value uDef points to. A uDef passed by value is returned
+// as is and cannot be injected into, a nil uDef returns an invalid Value.
+func backing(r Resource) reflect.Value {
+	if p, ok := r.(*protoBuilder); ok {
+		v := reflect.ValueOf(p.UDef)
+		if v.Kind() != reflect.Ptr {
+			return v
+		}
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		return v.Elem()
+	}
+	return reflect.ValueOf(r).Elem()
+}
+
+// backingType is the type of the backing struct, nil when there is none
+func backingType(r Resource) reflect.Type {
+	v := backing(r)
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Type()
+}
+
 func checkField(r Resource, field string) error {
 	if len(field) == 0 {
 		return nil
 	}
 
-	if reflect.ValueOf(r).Elem().Type().Name() == "protoBuilder" {
-		if !reflect.ValueOf(r).Elem().FieldByName("UDef").Elem().Elem().FieldByName(field).IsValid() {
-			return fmt.Errorf("in %s embedded Resource did not find field %s", r.ResourceName(), field)
-		}
-	} else {
-		if !reflect.ValueOf(r).Elem().FieldByName(field).IsValid() {
-			return fmt.Errorf("in %s Resource did not find field %s", r.ResourceName(), field)
+	if _, err := pathType(backingType(r), field); err != nil {
+		if _, ok := r.(*protoBuilder); ok {
+			return fmt.Errorf("in %s embedded Resource %v", r.ResourceName(), err)
 		}
+		return fmt.Errorf("in %s Resource %v", r.ResourceName(), err)
 	}
 	return nil
 }
 
-func copyValue(to Resource, toField string, from Resource, fromField string) {
+// copyValue injects the value at fromField of a Resource in toField of another,
+// fields are dotted paths which may index slices and maps
+func copyValue(to Resource, toField string, from Resource, fromField string) error {
 	if len(toField) == 0 || len(fromField) == 0 {
-		return
+		return nil
 	}
 
-	var fromValue reflect.Value
-	if reflect.ValueOf(from).Elem().Type().Name() == "protoBuilder" {
-		fromValue = reflect.ValueOf(from).Elem().FieldByName("UDef").Elem().Elem().FieldByName(fromField)
-	} else {
-		fromValue = reflect.ValueOf(from).Elem().FieldByName(fromField)
+	v, err := getPath(backing(from), fromField)
+	if err != nil {
+		return fmt.Errorf("unable to read %s of %s: %v", fromField, from.ResourceName(), err)
 	}
 
-	if reflect.ValueOf(to).Elem().Type().Name() == "protoBuilder" {
-		reflect.ValueOf(to).Elem().FieldByName("UDef").Elem().Elem().FieldByName(toField).Set(fromValue)
-	} else {
-		reflect.ValueOf(to).Elem().FieldByName(toField).Set(fromValue)
+	if err := setPath(backing(to), toField, v); err != nil {
+		return fmt.Errorf("unable to inject %s.%s into %s of %s: %v", from.ResourceName(), fromField, toField, to.ResourceName(), err)
 	}
+	return nil
 }
 
-func (lib *Lib) createSync(ctxt context.Context, resources []Resource, g *graph) (map[string]string, error) {
-	ordered := sort(g)
+// inject copies the values of every dependency of a Resource, or renders their
+// expression
+func inject(r Resource, cache map[string]Resource) error {
+	for _, dep := range dependencies(r, cache) {
+		var err error
+		if len(dep.Expression) > 0 {
+			err = evaluate(r, dep, cache)
+		} else {
+			err = copyValue(r, dep.ToField, cache[dep.FromResource], dep.FromField)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-	var err error
+// syncRun holds the values shared by the resources of a single createSync
+type syncRun struct {
+	cache    map[string]Resource
+	previous map[string]Record
+	// outputs lists the fields of a resource read by its dependents
+	outputs map[string][]string
+}
 
-	resourcesLeft := len(ordered)
-	maxAttempts := len(ordered)
+func newSyncRun(resources []Resource, previous map[string]Record) *syncRun {
+	run := &syncRun{
+		cache:    map[string]Resource{},
+		previous: previous,
+		outputs:  map[string][]string{},
+	}
 
-	buildCache := map[string]Resource{}
-	status := map[string]string{}
-	for maxAttempts > 0 && resourcesLeft > 0 && err == nil {
-		maxAttempts--
-		execList := []int{}
-		for _, i := range ordered {
+	for _, r := range resources {
+		run.cache[r.ResourceName()] = r
+	}
 
-			res := resources[i]
-			// check if we've already executed
-			if _, alreadyExecuted := buildCache[res.ResourceName()]; alreadyExecuted {
-				lib.logger("already executed", i)
+	for _, r := range resources {
+		for _, dep := range dependencies(r, run.cache) {
+			if len(dep.FromField) > 0 {
+				run.outputs[dep.FromResource] = append(run.outputs[dep.FromResource], dep.FromField)
+			}
+			if len(dep.Expression) == 0 {
 				continue
 			}
-
-			ready := true
-			for _, dep := range res.ResourceDependencies() {
-				if _, found := buildCache[dep.FromResource]; !found {
-					// cannot proceed as this resource cannot be processed
-					ready = false
-					break
+			if t, err := parseExpression(dep); err == nil {
+				for _, ref := range references(t) {
+					if len(ref.field) > 0 {
+						run.outputs[ref.resource] = append(run.outputs[ref.resource], ref.field)
+					}
 				}
 			}
-			if !ready {
-				break
-			}
-			execList = append(execList, i)
 		}
+	}
 
-		// execute nodes that are ready
-		var wg sync.WaitGroup
-		output := map[int]chan builderOutput{}
-
-		lib.logger("executing ", execList)
-		for _, i := range execList {
-			wg.Add(1)
-			output[i] = make(chan builderOutput, 1)
+	return run
+}
 
-			go func(b Resource, c chan builderOutput) {
-				defer wg.Done()
-				c <- lib.execute(ctxt, b, buildCache)
-			}(resources[i], output[i])
+func (lib *Lib) createSync(ctxt context.Context, resources []Resource, g *graph, previous map[string]Record) (map[string]Result, error) {
+	run := newSyncRun(resources, previous)
+	lib.resetOutcomes()
+
+	var mux sync.Mutex
+	results := map[string]Result{}
+	// created lists the resources updated by this run, in order of completion
+	created := []int{}
+
+	update := func(i int) error {
+		var e builderOutput
+		start := time.Now()
+		completed, err := lib.bounded(ctxt, resources[i], func(ctxt context.Context) error {
+			e = lib.execute(ctxt, resources[i], run)
+			return e.result
+		})
+		lib.measure(lib.updates, resources[i].ResourceName(), time.Since(start))
+
+		// an abandoned execute may still write e
+		out := builderOutput{result: err}
+		if completed {
+			out = e
+			out.result = err
 		}
 
-		wg.Wait()
+		// only a completed execute is recorded, an abandoned one has already
+		// been reported as failed
+		if completed && out.result == nil && out.detail.Action != Unchanged && lib.store != nil {
+			out.result = lib.record(ctxt, resources[i], out.detail.Status, out.hash, run.outputs[resources[i].ResourceName()])
+		}
 
-		errs := errorMap{}
-		for i, c := range output {
-			e := <-c
+		mux.Lock()
+		if completed && (out.result == nil || len(out.detail.Status) > 0) {
+			results[resources[i].ResourceName()] = out.detail
+		}
+		if out.result == nil && out.detail.Action != Unchanged {
+			created = append(created, i)
+		}
+		mux.Unlock()
 
-			if len(e.status) > 0 {
-				status[resources[i].ResourceName()] = e.status
-			}
+		if out.result != nil {
+			lib.logger("error executing resource", "resource", resources[i], "error", out.result)
+		}
 
-			if e.result != nil {
-				lib.logger("error executing resource", "resource", resources[i], "error", e)
-				errs[resources[i].ResourceName()] = e.result
-				continue
-			}
+		lib.observe(resources[i].ResourceName(), out.result)
+		return out.result
+	}
 
-			name := resources[i].ResourceName()
-			buildCache[name] = resources[i]
-		}
+	failures := lib.schedule(ctxt, work{
+		g:               g,
+		less:            lib.order(resources),
+		continueOnError: lib.continueOnError,
+		run:             update,
+		skipped:         skipped(resources),
+		canceled:        canceled(resources),
+	})
 
-		resourcesLeft -= len(execList) - len(errs)
+	errs := resourceErrors(resources, failures)
+	lib.observeSkipped(errs)
 
-		if len(errs) > 0 {
-			err = errs
-		}
+	if len(errs) > 0 && lib.rollback {
+		return results, &RollbackError{Err: errs, Failures: lib.rollbackSync(ctxt, resources, created)}
 	}
 
-	if resourcesLeft > 0 && err == nil {
-		err = errors.New("max attempts at computing resources exhausted, giving up")
+	if len(errs) > 0 {
+		return results, errs
 	}
 
-	return status, err
+	return results, nil
 }
 
-func (lib *Lib) execute(ctxt context.Context, r Resource, cache map[string]Resource) builderOutput {
-	for _, dep := range r.ResourceDependencies() {
-		copyValue(r, dep.ToField, cache[dep.FromResource], dep.FromField)
+func (lib *Lib) execute(ctxt context.Context, r Resource, run *syncRun) builderOutput {
+	if err := inject(r, run.cache); err != nil {
+		return builderOutput{result: err}
 	}
 
-	out, err := lib.decorator(r).Update(ctxt)
-	return builderOutput{out, err}
-}
+	var hash string
+	if lib.store != nil {
+		var err error
+		if hash, err = specHash(r); err != nil {
+			return builderOutput{result: err}
+		}
+	}
 
-func reverse(in []int) {
-	for i := len(in)/2 - 1; i >= 0; i-- {
-		opp := len(in) - 1 - i
-		in[i], in[opp] = in[opp], in[i]
+	if rec, found := run.previous[r.ResourceName()]; found && rec.Hash == hash {
+		lib.logger("skipping unchanged resource", r.ResourceName())
+		return builderOutput{detail: Result{Status: rec.Status, Action: Unchanged}, result: restoreOutputs(r, rec)}
 	}
+
+	var out Result
+	err := lib.retry(ctxt, r, func() error {
+		var err error
+		out, err = lib.update(ctxt, r)
+		return err
+	})
+
+	return builderOutput{detail: out, result: err, hash: hash}
 }
 
 func (lib *Lib) deleteSync(ctxt context.Context, resources []Resource, g *graph) error {
-	order := sort(g)
-	reverse(order)
+	lib.resetOutcomes()
+
+	// resources are scheduled over the reversed dependencies, so that a
+	// resource is only deleted after every resource depending on it
+	rg := g.transpose()
+
+	less := lib.order(resources)
 
-	lib.logger("order of deletion", order)
+	lib.logger("order of deletion", sortBy(rg, less))
 
-	var err error
+	remove := func(i int) error {
+		start := time.Now()
+		_, err := lib.bounded(ctxt, resources[i], func(ctxt context.Context) error {
+			err := lib.decorator(resources[i]).Delete(ctxt)
+			if err == nil && lib.store != nil {
+				err = lib.store.Remove(ctxt, resources[i].ResourceName())
+			}
+			return err
+		})
+		lib.measure(lib.deletes, resources[i].ResourceName(), time.Since(start))
 
-	for _, i := range order {
-		err = lib.decorator(resources[i]).Delete(ctxt)
 		if err != nil {
-			err = errorMap{resources[i].ResourceName(): err}
-			break
+			lib.logger("error deleting resource", "resource", resources[i], "error", err)
 		}
+
+		lib.observe(resources[i].ResourceName(), err)
+		return err
 	}
 
-	return err
+	failures := lib.schedule(ctxt, work{
+		g:               rg,
+		less:            less,
+		continueOnError: true,
+		run:             remove,
+		skipped:         skipped(resources),
+		canceled:        canceled(resources),
+	})
+
+	errs := resourceErrors(resources, failures)
+	lib.observeSkipped(errs)
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
 }
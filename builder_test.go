@@ -2,6 +2,9 @@ package graph
 
 import (
 	"context"
+	"errors"
+	"reflect"
+	"sync"
 	"testing"
 )
 
@@ -37,7 +40,7 @@ func TestCopyValue(t *testing.T) {
 	arn := "hello123"
 
 	kinesisResource := MakeResource("mykin", nil, &kinesis{ctxt, arn}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
-	deploymentResource := MakeResource("mydep1", []Dependency{{"mykin", "Arn", "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { d := x.(*deployment); return d.KinesisArn, nil }, func(x interface{}) error { return nil })
+	deploymentResource := MakeResource("mydep1", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { d := x.(*deployment); return d.KinesisArn, nil }, func(x interface{}) error { return nil })
 
 	copyValue(deploymentResource, "KinesisArn", kinesisResource, "Arn")
 
@@ -62,7 +65,7 @@ func TestSync(t *testing.T) {
 
 	kinesisResource := MakeResource(mykin, nil, &kinesis{ctxt, arn}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
 	dynamoResource := MakeResource("mydyn", nil, &dynamo{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
-	deploymentResource := MakeResource("mydep1", []Dependency{{"mykin", "Arn", "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { d := x.(*deployment); return d.KinesisArn, nil }, func(x interface{}) error { return nil })
+	deploymentResource := MakeResource("mydep1", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { d := x.(*deployment); return d.KinesisArn, nil }, func(x interface{}) error { return nil })
 
 	resources := []Resource{kinesisResource, dynamoResource, deploymentResource}
 
@@ -88,7 +91,7 @@ func TestInvalidDependency(t *testing.T) {
 	arn := "hello123"
 
 	kinesisResource := MakeResource(mykin, nil, &kinesis{ctxt, arn}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
-	deploymentResource := MakeResource("mydep1", []Dependency{{"mykin2", "Arn", "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { d := x.(*deployment); return d.KinesisArn, nil }, func(x interface{}) error { return nil })
+	deploymentResource := MakeResource("mydep1", []Dependency{{FromResource: "mykin2", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { d := x.(*deployment); return d.KinesisArn, nil }, func(x interface{}) error { return nil })
 
 	resources := []Resource{kinesisResource, deploymentResource}
 
@@ -100,3 +103,244 @@ func TestInvalidDependency(t *testing.T) {
 		t.Fatalf("expected sync to fail with invalid dependency")
 	}
 }
+
+func TestSyncCycle(t *testing.T) {
+	ctxt := context.Background()
+
+	kinesisResource := MakeResource("mykin", []Dependency{{FromResource: "mydep1", FromField: "KinesisArn", ToField: "Arn"}}, &kinesis{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
+	deploymentResource := MakeResource("mydep1", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
+	dynamoResource := MakeResource("mydyn", []Dependency{{FromResource: "mydyn"}}, &dynamo{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
+
+	resources := []Resource{kinesisResource, deploymentResource, dynamoResource}
+
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	_, err := lib.Sync(ctxt, resources, false)
+
+	ce, ok := err.(*CycleError)
+	if !ok {
+		t.Fatalf("expected a CycleError, got %v", err)
+	}
+
+	t.Logf("err = %v", err)
+
+	if !reflect.DeepEqual(ce.Cycles, [][]string{{"mykin", "mydep1"}, {"mydyn"}}) {
+		t.Fatalf("unexpected cycles %v", ce.Cycles)
+	}
+}
+
+func TestSyncDelete(t *testing.T) {
+	ctxt := context.Background()
+
+	var mux sync.Mutex
+	deleted := []string{}
+	del := func(name string, err error) func(interface{}) error {
+		return func(x interface{}) error {
+			if err != nil {
+				return err
+			}
+			mux.Lock()
+			defer mux.Unlock()
+			deleted = append(deleted, name)
+			return nil
+		}
+	}
+	update := func(x interface{}) (string, error) { return "", nil }
+
+	kinesisResource := MakeResource("mykin", nil, &kinesis{ctxt: ctxt}, update, del("mykin", nil))
+	dynamoResource := MakeResource("mydyn", nil, &dynamo{ctxt: ctxt}, update, del("mydyn", nil))
+	deploymentResource := MakeResource("mydep1", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, update, del("mydep1", errors.New("deployment stuck")))
+	deployment2Resource := MakeResource("mydep2", []Dependency{{FromResource: "mydyn"}}, &deployment{ctxt: ctxt}, update, del("mydep2", nil))
+
+	resources := []Resource{kinesisResource, dynamoResource, deploymentResource, deployment2Resource}
+
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	_, err := lib.Sync(ctxt, resources, true)
+
+	em, ok := err.(ErrorMapper)
+	if !ok {
+		t.Fatalf("expected an ErrorMapper, got %v", err)
+	}
+
+	errs := em.ErrorMap()
+	if len(errs) != 2 || errs["mydep1"] == nil {
+		t.Fatalf("expected mydep1 to fail and block mykin, got %v", errs)
+	}
+
+	if se, ok := errs["mykin"].(*SkippedError); !ok || se.Ancestor != "mydep1" {
+		t.Fatalf("expected mykin to be skipped because of mydep1, got %v", errs["mykin"])
+	}
+
+	if !reflect.DeepEqual(deleted, []string{"mydep2", "mydyn"}) {
+		t.Fatalf("expected mydep2 to be deleted before mydyn, got %v", deleted)
+	}
+}
+
+func TestSyncContinueOnError(t *testing.T) {
+	ctxt := context.Background()
+
+	ok := func(x interface{}) (string, error) { return "done", nil }
+	fail := func(x interface{}) (string, error) { return "", errors.New("kinesis limit exceeded") }
+	del := func(x interface{}) error { return nil }
+
+	kinesisResource := MakeResource("mykin", nil, &kinesis{ctxt: ctxt}, fail, del)
+	dynamoResource := MakeResource("mydyn", nil, &dynamo{ctxt: ctxt}, ok, del)
+	deploymentResource := MakeResource("mydep1", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, ok, del)
+	deployment2Resource := MakeResource("mydep2", []Dependency{{FromResource: "mydep1"}}, &deployment{ctxt: ctxt}, ok, del)
+	deployment3Resource := MakeResource("mydep3", []Dependency{{FromResource: "mydyn"}}, &deployment{ctxt: ctxt}, ok, del)
+
+	resources := []Resource{kinesisResource, dynamoResource, deploymentResource, deployment2Resource, deployment3Resource}
+
+	lib := New(&Opts{CustomLogger: t.Log, ContinueOnError: true})
+
+	status, err := lib.Sync(ctxt, resources, false)
+
+	em, isMapper := err.(ErrorMapper)
+	if !isMapper {
+		t.Fatalf("expected an ErrorMapper, got %v", err)
+	}
+
+	errs := em.ErrorMap()
+	if len(errs) != 3 || errs["mykin"] == nil {
+		t.Fatalf("expected mykin to fail and block its descendants, got %v", errs)
+	}
+
+	for _, name := range []string{"mydep1", "mydep2"} {
+		if se, ok := errs[name].(*SkippedError); !ok || se.Ancestor != "mykin" {
+			t.Fatalf("expected %s to be skipped because of mykin, got %v", name, errs[name])
+		}
+	}
+
+	if status["mydyn"] != "done" || status["mydep3"] != "done" {
+		t.Fatalf("expected unrelated resources to be built, status %v", status)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	ctxt := context.Background()
+
+	ok := func(x interface{}) (string, error) { return "", nil }
+	del := func(x interface{}) error { return nil }
+
+	resources := []Resource{
+		MakeResource("mykin", nil, &kinesis{ctxt: ctxt}, ok, del),
+		MakeResource("mysvc", nil, &service{}, ok, del),
+		MakeResource("mykin", nil, &dynamo{ctxt: ctxt}, ok, del),
+		MakeResource("mydep1", []Dependency{{FromResource: "mysvc", FromField: "Port", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, ok, del),
+		MakeResource("mydep2", []Dependency{{FromResource: "mykin", FromField: "ctxt", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, ok, del),
+		MakeResource("mydep3", []Dependency{{FromResource: "mysvc", FromField: "Status.Arn", ToField: "Tags[arn]"}, {FromResource: "mysvc", FromField: "Status.Ports[0].Number", ToField: "Port"}}, &service{}, ok, del),
+		MakeResource("mydep4", []Dependency{{FromResource: "mysvc", FromField: "Port", ToField: "Status.Ports[0].Number"}}, &service{}, ok, del),
+	}
+
+	err := check(resources)
+
+	ce, isCheck := err.(*CheckError)
+	if !isCheck {
+		t.Fatalf("expected a CheckError, got %v", err)
+	}
+
+	expected := []string{
+		"duplicate Resource name mykin",
+		"Resource mydep1 cannot inject mysvc.Port of type int64 into KinesisArn of type string",
+		"in mykin embedded Resource field ctxt is unexported",
+		"Resource mydep4 cannot inject mysvc.Port of type int64 into Status.Ports[0].Number of type int32",
+	}
+	if len(ce.Errors) != len(expected) {
+		t.Fatalf("expected %d problems, got %v", len(expected), ce)
+	}
+	for i, msg := range expected {
+		if ce.Errors[i].Error() != msg {
+			t.Fatalf("expected %q, got %q", msg, ce.Errors[i])
+		}
+	}
+}
+
+// valRes implements Resource with value receivers, it is not comparable
+type valRes struct {
+	Name string
+	Tags []string
+}
+
+func (v valRes) ResourceName() string                        { return v.Name }
+func (v valRes) ResourceDependencies() []Dependency          { return nil }
+func (v valRes) Update(ctxt context.Context) (string, error) { return "", nil }
+func (v valRes) Delete(ctxt context.Context) error           { return nil }
+
+func TestCheckValueResource(t *testing.T) {
+	err := check([]Resource{valRes{Name: "myval", Tags: []string{"env"}}})
+
+	ce, isCheck := err.(*CheckError)
+	if !isCheck || len(ce.Errors) != 1 || ce.Errors[0].Error() != "expected myval Resource to be implemented with a pointer to struct" {
+		t.Fatalf("expected a pointer to struct error, got %v", err)
+	}
+}
+
+func TestSyncDependencyKinds(t *testing.T) {
+	ctxt := context.Background()
+
+	del := func(x interface{}) error { return nil }
+	deployed := func(x interface{}) (string, error) { return "deployed " + x.(*deployment).KinesisArn, nil }
+
+	resources := []Resource{
+		MakeResource("mydep1", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn", Kind: Soft}, {FromResource: "mydyn", Kind: OrderOnly}}, &deployment{ctxt: ctxt}, deployed, del),
+		MakeResource("mydep2", []Dependency{{FromResource: "mycache", FromField: "Arn", ToField: "KinesisArn", Kind: Soft}, {FromResource: "mydyn", Kind: Soft}}, &deployment{ctxt: ctxt}, deployed, del),
+		MakeResource("mykin", nil, &kinesis{ctxt: ctxt, Arn: "hello123"}, func(x interface{}) (string, error) { return "", nil }, del),
+		MakeResource("mydyn", nil, &dynamo{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, del),
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	plan, err := lib.Plan(ctxt, resources, false)
+	if err != nil {
+		t.Fatalf("unable to plan %v", err)
+	}
+	if !reflect.DeepEqual(plan.Waves, [][]string{{"mykin", "mydyn"}, {"mydep1", "mydep2"}}) || len(plan.Injections) != 1 {
+		t.Fatalf("unexpected plan %v", plan)
+	}
+
+	status, err := lib.Sync(ctxt, resources, false)
+	if err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+	if status["mydep1"] != "deployed hello123" || status["mydep2"] != "deployed " {
+		t.Fatalf("unexpected status %v", status)
+	}
+
+	for _, dep := range []Dependency{
+		{FromResource: "mycache", Kind: OrderOnly},
+		{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn", Kind: OrderOnly},
+		{FromResource: "mykin", Kind: "optional"},
+	} {
+		resources[1] = MakeResource("mydep2", []Dependency{dep}, &deployment{ctxt: ctxt}, deployed, del)
+		if _, err := lib.Sync(ctxt, resources, false); err == nil {
+			t.Fatalf("expected dependency %v to be rejected", dep)
+		}
+	}
+}
+
+func TestSyncCyclesSharingResource(t *testing.T) {
+	ctxt := context.Background()
+
+	ok := func(x interface{}) (string, error) { return "", nil }
+	del := func(x interface{}) error { return nil }
+
+	resources := []Resource{
+		MakeResource("mykin", []Dependency{{FromResource: "mydep1"}}, &kinesis{ctxt: ctxt}, ok, del),
+		MakeResource("mydep1", []Dependency{{FromResource: "mykin"}, {FromResource: "mydep2"}}, &deployment{ctxt: ctxt}, ok, del),
+		MakeResource("mydep2", []Dependency{{FromResource: "mykin"}}, &deployment{ctxt: ctxt}, ok, del),
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	_, err := lib.Sync(ctxt, resources, false)
+
+	ce, isCycle := err.(*CycleError)
+	if !isCycle {
+		t.Fatalf("expected a CycleError, got %v", err)
+	}
+
+	if !reflect.DeepEqual(ce.Cycles, [][]string{{"mykin", "mydep1"}, {"mykin", "mydep2", "mydep1"}}) {
+		t.Fatalf("expected both cycles through mykin, got %v", ce.Cycles)
+	}
+}
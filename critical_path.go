@@ -0,0 +1,114 @@
+package graph
+
+import "time"
+
+// CriticalPath reports the chain of resources that bounds the duration of a Sync,
+// based on the durations measured by the last Sync of every Resource.
+type CriticalPath struct {
+	// Path lists the resources of the longest chain, in order of processing.
+	Path []string
+	// Duration of the longest chain.
+	Duration time.Duration
+	// Timings of every Resource keyed by name.
+	Timings map[string]Timing
+}
+
+// Timing schedules a Resource relative to the start of a Sync without limits
+// on concurrency.
+type Timing struct {
+	// Duration measured by the last Sync, zero if the Resource was not processed.
+	Duration       time.Duration
+	EarliestStart  time.Duration
+	EarliestFinish time.Duration
+	LatestStart    time.Duration
+	LatestFinish   time.Duration
+	// Slack is how much the Resource may be delayed without delaying the Sync,
+	// it is zero for resources on the critical path.
+	Slack time.Duration
+}
+
+// measure records the duration of a processed Resource
+func (lib *Lib) measure(durations map[string]time.Duration, name string, d time.Duration) {
+	lib.mux.Lock()
+	defer lib.mux.Unlock()
+
+	durations[name] = d
+}
+
+// CriticalPath validates the Resource slice like Sync, and computes the critical
+// path through its dependencies using the Update durations, or Delete durations
+// when toDelete is set, measured by previous syncs of the Lib.
+func (lib *Lib) CriticalPath(resources []Resource, toDelete bool) (*CriticalPath, error) {
+	g, err := prepare(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	measured := lib.updates
+	if toDelete {
+		g = g.transpose()
+		measured = lib.deletes
+	}
+
+	lib.mux.Lock()
+	durations := make([]time.Duration, len(resources))
+	for i, r := range resources {
+		durations[i] = measured[r.ResourceName()]
+	}
+	lib.mux.Unlock()
+
+	ordered := sortBy(g, lib.order(resources))
+	earliest := make([]time.Duration, len(resources))
+	latest := make([]time.Duration, len(resources))
+	// critical parent of every vertex, the one finishing last
+	parent := make([]int, len(resources))
+
+	cp := &CriticalPath{Timings: map[string]Timing{}}
+	last := -1
+
+	for i := range parent {
+		parent[i] = -1
+	}
+
+	for _, v := range ordered {
+		finish := earliest[v] + durations[v]
+		if last == -1 || finish > cp.Duration {
+			cp.Duration, last = finish, v
+		}
+		for _, w := range g.adjascent(v) {
+			if parent[w] == -1 || finish > earliest[w] {
+				earliest[w], parent[w] = finish, v
+			}
+		}
+	}
+
+	for k := len(ordered) - 1; k >= 0; k-- {
+		v := ordered[k]
+		latest[v] = cp.Duration
+		for _, w := range g.adjascent(v) {
+			if start := latest[w] - durations[w]; start < latest[v] {
+				latest[v] = start
+			}
+		}
+	}
+
+	for _, v := range ordered {
+		cp.Timings[resources[v].ResourceName()] = Timing{
+			Duration:       durations[v],
+			EarliestStart:  earliest[v],
+			EarliestFinish: earliest[v] + durations[v],
+			LatestStart:    latest[v] - durations[v],
+			LatestFinish:   latest[v],
+			Slack:          latest[v] - durations[v] - earliest[v],
+		}
+	}
+
+	for v := last; v != -1; v = parent[v] {
+		cp.Path = append(cp.Path, resources[v].ResourceName())
+	}
+	for i, j := 0, len(cp.Path)-1; i < j; i, j = i+1, j-1 {
+		cp.Path[i], cp.Path[j] = cp.Path[j], cp.Path[i]
+	}
+
+	return cp, nil
+}
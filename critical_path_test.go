@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCriticalPath(t *testing.T) {
+	ctxt := context.Background()
+
+	update := func(d time.Duration) func(interface{}) (string, error) {
+		return func(x interface{}) (string, error) { time.Sleep(d); return "", nil }
+	}
+	del := func(x interface{}) error { return nil }
+
+	kinesisResource := MakeResource("mykin", nil, &kinesis{ctxt: ctxt}, update(20*time.Millisecond), del)
+	dynamoResource := MakeResource("mydyn", nil, &dynamo{ctxt: ctxt}, update(0), del)
+	deploymentResource := MakeResource("mydep", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}, {FromResource: "mydyn"}}, &deployment{ctxt: ctxt}, update(0), del)
+	alarmResource := MakeResource("myalarm", []Dependency{{FromResource: "mydyn"}}, &dynamo{ctxt: ctxt}, update(0), del)
+
+	resources := []Resource{kinesisResource, dynamoResource, deploymentResource, alarmResource}
+
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	if _, err := lib.Sync(ctxt, resources, false); err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+
+	if lib.updates["mykin"] < 20*time.Millisecond {
+		t.Fatalf("expected Update of mykin to be timed, got %v", lib.updates["mykin"])
+	}
+
+	// replace measurements for a predictable report
+	lib.updates = map[string]time.Duration{"mykin": 10 * time.Second, "mydyn": 2 * time.Second, "mydep": 3 * time.Second, "myalarm": time.Second}
+
+	cp, err := lib.CriticalPath(resources, false)
+	if err != nil {
+		t.Fatalf("unable to compute critical path %v", err)
+	}
+
+	t.Logf("critical path = %+v", cp)
+
+	if !reflect.DeepEqual(cp.Path, []string{"mykin", "mydep"}) || cp.Duration != 13*time.Second {
+		t.Fatalf("unexpected critical path %v of %v", cp.Path, cp.Duration)
+	}
+
+	expected := Timing{Duration: 2 * time.Second, EarliestFinish: 2 * time.Second, LatestStart: 8 * time.Second, LatestFinish: 10 * time.Second, Slack: 8 * time.Second}
+	if cp.Timings["mydyn"] != expected {
+		t.Fatalf("unexpected mydyn timing %+v", cp.Timings["mydyn"])
+	}
+
+	if cp.Timings["mykin"].Slack != 0 || cp.Timings["myalarm"].Slack != 10*time.Second {
+		t.Fatalf("unexpected slack %+v", cp.Timings)
+	}
+
+	lib.deletes = map[string]time.Duration{"mykin": time.Second, "mydyn": time.Second, "mydep": 5 * time.Second, "myalarm": time.Second}
+
+	cp, err = lib.CriticalPath(resources, true)
+	if err != nil {
+		t.Fatalf("unable to compute critical path %v", err)
+	}
+
+	if !reflect.DeepEqual(cp.Path, []string{"mydep", "mykin"}) || cp.Duration != 6*time.Second {
+		t.Fatalf("unexpected delete critical path %v of %v", cp.Path, cp.Duration)
+	}
+}
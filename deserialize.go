@@ -1,27 +1,25 @@
 package graph
 
-func buildGraph(resources []Resource) *graph {
-	parents := map[int]map[int]bool{}
-	indexes := map[string]int{}
+// buildGraph creates a Graph with an edge from every resource to the resources
+// depending on it. Vertices are added in slice order, so the vertex of a resource
+// is its position in the slice. As Graph adds vertices and edges in constant time,
+// it runs in linear time of resources plus dependencies.
+func buildGraph(resources []Resource) *Graph[Resource] {
+	index := indexes(resources)
 
-	for i := range resources {
-		indexes[resources[i].ResourceName()] = i
+	dag := NewGraph[Resource]()
+	for _, r := range resources {
+		dag.AddVertex(r)
 	}
 
-	for i := range resources {
-		parents[i] = map[int]bool{}
-		for _, dep := range resources[i].ResourceDependencies() {
-			parents[i][indexes[dep.FromResource]] = true
+	// repeated dependencies on a resource add a single edge
+	for _, r := range resources {
+		for _, dep := range dependencies(r, index) {
+			for _, name := range parents(dep) {
+				dag.AddEdge(resources[index[name]], r)
+			}
 		}
 	}
 
-	g := newGraph(len(resources))
-
-	for w, m := range parents {
-		for k := range m {
-			g.addEdge(k, w)
-		}
-	}
-
-	return g
+	return dag
 }
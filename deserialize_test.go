@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"reflect"
 	"testing"
 )
 
@@ -12,7 +13,7 @@ func TestBuildGraph(t *testing.T) {
 
 	kinesisResource := MakeResource(mykin, nil, &kinesis{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
 	dynamoResource := MakeResource("mydyn", nil, &dynamo{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
-	deploymentResource := MakeResource("mydep1", []Dependency{{"mykin", "Arn", "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
+	deploymentResource := MakeResource("mydep1", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
 
 	resources := []Resource{kinesisResource, dynamoResource, deploymentResource}
 
@@ -20,11 +21,15 @@ func TestBuildGraph(t *testing.T) {
 
 	t.Logf("graph = %v\n", g)
 
-	if g.vertices() != 3 {
+	if !reflect.DeepEqual(g.Vertices(), resources) {
 		t.Fatal("vertices incorrect")
 	}
 
-	if len(g.adjascent(0)) != 1 || g.adjascent(0)[0] != 2 {
+	if !reflect.DeepEqual(g.Successors(kinesisResource), []Resource{deploymentResource}) || len(g.Successors(dynamoResource)) != 0 {
+		t.Fatal("incorrect edges")
+	}
+
+	if !reflect.DeepEqual(g.Predecessors(deploymentResource), []Resource{kinesisResource}) {
 		t.Fatal("incorrect edges")
 	}
 }
@@ -0,0 +1,154 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	gosort "sort"
+)
+
+// Reader may be implemented by a Resource to report the state that actually exists.
+type Reader interface {
+	// Read returns observed field values keyed by the name of the field in the
+	// struct implementing the Resource.
+	Read(ctxt context.Context) (map[string]interface{}, error)
+}
+
+// FieldDiff describes a field whose observed value differs from its declared value.
+type FieldDiff struct {
+	Field    string
+	Declared interface{}
+	Observed interface{}
+}
+
+func (fd FieldDiff) String() string {
+	return fmt.Sprintf("%s: declared %v, observed %v", fd.Field, fd.Declared, fd.Observed)
+}
+
+// Diff walks the DAG of resources and reads every Resource implementing Reader. Declared
+// fields are compared with the observed ones. Fields injected from a dependency are
+// compared with the output of the dependency recorded in the StateStore, or as declared
+// when there is none. Differences are returned keyed by resource name, resources without
+// differences are omitted. Read errors are reported through ErrorMapper. No resource is
+// updated, deleted or modified.
+func (lib *Lib) Diff(ctxt context.Context, resources []Resource) (map[string][]FieldDiff, error) {
+	g, err := prepare(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	var records map[string]Record
+	if lib.store != nil {
+		if records, err = lib.store.Load(ctxt); err != nil {
+			return nil, err
+		}
+	}
+
+	lib.logger("starting diff")
+
+	cache := map[string]Resource{}
+	for _, r := range resources {
+		cache[r.ResourceName()] = r
+	}
+
+	diffs := map[string][]FieldDiff{}
+	errs := errorMap{}
+
+	for _, i := range sortBy(g, lib.order(resources)) {
+		r := resources[i]
+		reader, ok := r.(Reader)
+		if !ok {
+			continue
+		}
+
+		injected, err := recordedInputs(r, cache, records)
+		if err != nil {
+			lib.logger("error reading recorded inputs", "resource", r, "error", err)
+			errs[r.ResourceName()] = err
+			continue
+		}
+
+		observed, err := reader.Read(ctxt)
+		if err != nil {
+			lib.logger("error reading resource", "resource", r, "error", err)
+			errs[r.ResourceName()] = err
+			continue
+		}
+
+		if d := diffFields(r, injected, observed); len(d) > 0 {
+			diffs[r.ResourceName()] = d
+		}
+	}
+
+	if len(errs) > 0 {
+		return diffs, errs
+	}
+
+	return diffs, nil
+}
+
+// recordedInputs returns the values Sync would inject into the fields of a Resource,
+// read from the outputs recorded for its dependencies. Dependencies without a recorded
+// output, or with an expression, are left out.
+func recordedInputs(r Resource, cache map[string]Resource, records map[string]Record) (map[string]reflect.Value, error) {
+	values := map[string]reflect.Value{}
+
+	for _, dep := range dependencies(r, cache) {
+		if len(dep.ToField) == 0 || len(dep.FromField) == 0 {
+			continue
+		}
+
+		value, found := records[dep.FromResource].Outputs[dep.FromField]
+		if !found {
+			continue
+		}
+
+		v, err := decodeField(r, dep.ToField, value)
+		if err != nil {
+			return nil, err
+		}
+		values[dep.ToField] = v
+	}
+
+	return values, nil
+}
+
+// diffFields compares observed values with the injected value or the exported field
+// at the same path, observed values are converted to the type of the field when possible
+func diffFields(r Resource, injected map[string]reflect.Value, observed map[string]interface{}) []FieldDiff {
+	diffs := []FieldDiff{}
+
+	for field, value := range observed {
+		f, found := injected[field]
+		if !found {
+			var err error
+			if f, err = getPath(backing(r), field); err != nil {
+				continue
+			}
+		}
+
+		declared := f.Interface()
+		if !equalValue(f, value) {
+			diffs = append(diffs, FieldDiff{field, declared, value})
+		}
+	}
+
+	gosort.Slice(diffs, func(i, j int) bool { return diffs[i].Field < diffs[j].Field })
+
+	return diffs
+}
+
+func equalValue(declared reflect.Value, observed interface{}) bool {
+	if observed == nil {
+		return declared.IsZero()
+	}
+
+	o := reflect.ValueOf(observed)
+	// numbers convert to strings as runes, which is never what is observed
+	numberToString := declared.Kind() == reflect.String && o.Kind() != reflect.String
+	if o.Type() != declared.Type() && o.Type().ConvertibleTo(declared.Type()) && !numberToString {
+		o = o.Convert(declared.Type())
+	}
+
+	return reflect.DeepEqual(declared.Interface(), o.Interface())
+}
@@ -0,0 +1,101 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type observedStream struct {
+	Depends
+	ShardCount int
+	Arn        string
+	observed   map[string]interface{}
+	err        error
+}
+
+func (s *observedStream) Update(ctxt context.Context) (string, error) {
+	return "", errors.New("diff must not update")
+}
+
+func (s *observedStream) Delete(ctxt context.Context) error {
+	return errors.New("diff must not delete")
+}
+
+func (s *observedStream) Read(ctxt context.Context) (map[string]interface{}, error) {
+	return s.observed, s.err
+}
+
+func TestDiff(t *testing.T) {
+	ctxt := context.Background()
+
+	kin := &observedStream{
+		Depends:    Depends{Name: "mykin"},
+		ShardCount: 10,
+		Arn:        "hello123",
+		observed:   map[string]interface{}{"ShardCount": 5.0, "Arn": "hello123", "Unknown": true},
+	}
+	dep := &observedStream{
+		Depends:  Depends{Name: "mydep", Dependencies: []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "Arn"}}},
+		observed: map[string]interface{}{"Arn": "old123"},
+	}
+	broken := &observedStream{
+		Depends: Depends{Name: "mybroken"},
+		err:     errors.New("access denied"),
+	}
+
+	// injected fields are compared with the recorded outputs of their dependencies
+	store := NewMemoryStore()
+	if err := store.Save(ctxt, Record{Name: "mykin", Outputs: map[string]interface{}{"Arn": "hello123"}}); err != nil {
+		t.Fatalf("unable to save %v", err)
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log, StateStore: store})
+
+	diffs, err := lib.Diff(ctxt, []Resource{dep, kin, broken})
+
+	em, ok := err.(ErrorMapper)
+	if !ok || em.ErrorMap()["mybroken"] == nil {
+		t.Fatalf("expected read error of mybroken, got %v", err)
+	}
+
+	t.Logf("diffs = %v", diffs)
+
+	if !reflect.DeepEqual(diffs["mykin"], []FieldDiff{{"ShardCount", 10, 5.0}}) {
+		t.Fatalf("unexpected mykin diff %v", diffs["mykin"])
+	}
+
+	if !reflect.DeepEqual(diffs["mydep"], []FieldDiff{{"Arn", "hello123", "old123"}}) {
+		t.Fatalf("expected recorded value to be compared, got %v", diffs["mydep"])
+	}
+
+	if len(dep.Arn) > 0 {
+		t.Fatalf("expected diff not to inject, got %s", dep.Arn)
+	}
+}
+
+func TestDiffWithoutOutputs(t *testing.T) {
+	ctxt := context.Background()
+
+	kin := &observedStream{
+		Depends:  Depends{Name: "mykin"},
+		observed: map[string]interface{}{},
+	}
+	dep := &observedStream{
+		Depends:  Depends{Name: "mydep", Dependencies: []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "Arn"}}},
+		Arn:      "arn:real",
+		observed: map[string]interface{}{"Arn": "arn:real"},
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log, StateStore: NewMemoryStore()})
+
+	diffs, err := lib.Diff(ctxt, []Resource{kin, dep})
+	if err != nil {
+		t.Fatalf("unable to diff %v", err)
+	}
+
+	if len(diffs) != 0 || dep.Arn != "arn:real" {
+		t.Fatalf("expected fields of resources without outputs to be compared as declared, got %v, Arn %s", diffs, dep.Arn)
+	}
+}
@@ -1,6 +1,11 @@
 package graph
 
-import "strings"
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // ErrorMapper enables query into a map of errors
 type ErrorMapper interface {
@@ -23,3 +28,116 @@ func (es errorMap) Error() string {
 func (es errorMap) ErrorMap() map[string]error {
 	return es
 }
+
+// resourceErrors keys errors of resource indexes by resource name
+func resourceErrors(resources []Resource, errs map[int]error) errorMap {
+	em := errorMap{}
+	for i, err := range errs {
+		em[resources[i].ResourceName()] = err
+	}
+	return em
+}
+
+// CycleError is returned when resource dependencies form one or more cycles.
+// Every elementary cycle is listed, resource names in dependency order: a
+// resource is followed by the resource that depends on it, and the last one
+// depends on the first.
+type CycleError struct {
+	Cycles [][]string
+}
+
+func (ce *CycleError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("dependency cycles detected:")
+	for _, cycle := range ce.Cycles {
+		sb.WriteString(" ")
+		for _, name := range cycle {
+			sb.WriteString(name)
+			sb.WriteString(" -> ")
+		}
+		if len(cycle) > 0 {
+			sb.WriteString(cycle[0])
+		}
+		sb.WriteString(";")
+	}
+	return sb.String()
+}
+
+// CheckError is returned when resources are incorrectly specified, it lists every
+// problem found in the order of the resource slice.
+type CheckError struct {
+	Errors []error
+}
+
+func (ce *CheckError) Error() string {
+	msgs := make([]string, len(ce.Errors))
+	for i, err := range ce.Errors {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the problems found
+func (ce *CheckError) Unwrap() []error {
+	return ce.Errors
+}
+
+// SkippedError is reported for a resource that was not processed because a
+// resource it is blocked on failed.
+type SkippedError struct {
+	// Resource is the skipped resource.
+	Resource string
+	// Ancestor is the failed resource. It is a resource the skipped resource
+	// depends on when creating, and a resource depending on it when deleting.
+	Ancestor string
+}
+
+func (se *SkippedError) Error() string {
+	return fmt.Sprintf("skipped %s as %s failed", se.Resource, se.Ancestor)
+}
+
+// skipped builds the SkippedError of resource i blocked on the failed resource
+func skipped(resources []Resource) func(i, failed int) error {
+	return func(i, failed int) error {
+		return &SkippedError{Resource: resources[i].ResourceName(), Ancestor: resources[failed].ResourceName()}
+	}
+}
+
+// TimeoutError is reported for a Resource that did not complete within its timeout.
+type TimeoutError struct {
+	Resource string
+	Timeout  time.Duration
+}
+
+func (te *TimeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %v", te.Resource, te.Timeout)
+}
+
+// Unwrap returns context.DeadlineExceeded
+func (te *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+// CanceledError is reported for a Resource that was interrupted, or not started,
+// because the context passed to the Lib was done.
+type CanceledError struct {
+	Resource string
+	// Err is the error of the context.
+	Err error
+}
+
+func (ce *CanceledError) Error() string {
+	return fmt.Sprintf("%s canceled: %v", ce.Resource, ce.Err)
+}
+
+// Unwrap returns the error of the context
+func (ce *CanceledError) Unwrap() error {
+	return ce.Err
+}
+
+// canceled builds the CanceledError of resource i
+func canceled(resources []Resource) func(i int, err error) error {
+	return func(i int, err error) error {
+		return &CanceledError{Resource: resources[i].ResourceName(), Err: err}
+	}
+}
@@ -0,0 +1,173 @@
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// outcome of a Resource in the last Sync
+type outcome int
+
+const (
+	outcomeUnknown outcome = iota
+	outcomeSucceeded
+	outcomeFailed
+	outcomeSkipped
+)
+
+// dotColors and mermaidColors fill nodes by outcome of the last Sync
+var dotColors = map[outcome]string{
+	outcomeSucceeded: "palegreen",
+	outcomeFailed:    "salmon",
+	outcomeSkipped:   "lightgrey",
+}
+
+var mermaidColors = map[outcome]string{
+	outcomeSucceeded: "fill:#98fb98",
+	outcomeFailed:    "fill:#fa8072",
+	outcomeSkipped:   "fill:#d3d3d3",
+}
+
+var outcomeNames = map[outcome]string{
+	outcomeSucceeded: "succeeded",
+	outcomeFailed:    "failed",
+	outcomeSkipped:   "skipped",
+}
+
+// resetOutcomes forgets the outcomes of previous runs, a Sync starts from a
+// blank slate
+func (lib *Lib) resetOutcomes() {
+	lib.mux.Lock()
+	defer lib.mux.Unlock()
+
+	lib.outcomes = map[string]outcome{}
+}
+
+// observe records the outcome of a processed Resource
+func (lib *Lib) observe(name string, err error) {
+	lib.mux.Lock()
+	defer lib.mux.Unlock()
+
+	if err != nil {
+		lib.outcomes[name] = outcomeFailed
+	} else {
+		lib.outcomes[name] = outcomeSucceeded
+	}
+}
+
+// observeSkipped records the resources that were skipped by the scheduler
+func (lib *Lib) observeSkipped(errs errorMap) {
+	lib.mux.Lock()
+	defer lib.mux.Unlock()
+
+	for name, err := range errs {
+		if _, ok := err.(*SkippedError); ok {
+			lib.outcomes[name] = outcomeSkipped
+		}
+	}
+}
+
+func (lib *Lib) outcome(name string) outcome {
+	lib.mux.Lock()
+	defer lib.mux.Unlock()
+
+	return lib.outcomes[name]
+}
+
+// edgeLabel describes the injection of a Dependency, if any
+func edgeLabel(dep Dependency) string {
+	if len(dep.Expression) > 0 {
+		return dep.Expression + " -> " + dep.ToField
+	}
+	if len(dep.FromField) == 0 {
+		return ""
+	}
+	return dep.FromField + " -> " + dep.ToField
+}
+
+// ExportDOT writes the DAG of resources in Graphviz DOT format. Nodes are resource names
+// and edges are dependencies, labelled with the injected fields. Resources processed by
+// the last Sync of the Lib are coloured by their outcome.
+func (lib *Lib) ExportDOT(w io.Writer, resources []Resource) error {
+	err := check(resources)
+	if err != nil {
+		return err
+	}
+
+	index := indexes(resources)
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "digraph resources {")
+	for _, r := range resources {
+		if color, ok := dotColors[lib.outcome(r.ResourceName())]; ok {
+			fmt.Fprintf(bw, "  %q [style=filled, fillcolor=%s];\n", r.ResourceName(), color)
+		} else {
+			fmt.Fprintf(bw, "  %q;\n", r.ResourceName())
+		}
+	}
+	for _, r := range resources {
+		for _, dep := range dependencies(r, index) {
+			for _, from := range parents(dep) {
+				if label := edgeLabel(dep); len(label) > 0 {
+					fmt.Fprintf(bw, "  %q -> %q [label=%q];\n", from, r.ResourceName(), label)
+				} else {
+					fmt.Fprintf(bw, "  %q -> %q;\n", from, r.ResourceName())
+				}
+			}
+		}
+	}
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+// ExportMermaid writes the DAG of resources as a Mermaid flowchart, see ExportDOT.
+func (lib *Lib) ExportMermaid(w io.Writer, resources []Resource) error {
+	err := check(resources)
+	if err != nil {
+		return err
+	}
+
+	// mermaid node ids are restricted, resources are identified by position
+	ids := map[string]string{}
+	for i, r := range resources {
+		ids[r.ResourceName()] = fmt.Sprintf("n%d", i)
+	}
+
+	quote := strings.NewReplacer(`"`, "#quot;")
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "graph TD")
+	for _, r := range resources {
+		fmt.Fprintf(bw, "  %s[\"%s\"]\n", ids[r.ResourceName()], quote.Replace(r.ResourceName()))
+	}
+	for _, r := range resources {
+		for _, dep := range dependencies(r, ids) {
+			for _, from := range parents(dep) {
+				if label := edgeLabel(dep); len(label) > 0 {
+					fmt.Fprintf(bw, "  %s -->|\"%s\"| %s\n", ids[from], quote.Replace(label), ids[r.ResourceName()])
+				} else {
+					fmt.Fprintf(bw, "  %s --> %s\n", ids[from], ids[r.ResourceName()])
+				}
+			}
+		}
+	}
+	for o := outcomeSucceeded; o <= outcomeSkipped; o++ {
+		members := []string{}
+		for _, r := range resources {
+			if lib.outcome(r.ResourceName()) == o {
+				members = append(members, ids[r.ResourceName()])
+			}
+		}
+		if len(members) > 0 {
+			fmt.Fprintf(bw, "  classDef %s %s\n", outcomeNames[o], mermaidColors[o])
+			fmt.Fprintf(bw, "  class %s %s\n", strings.Join(members, ","), outcomeNames[o])
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,84 @@
+package graph
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func exportResources(ctxt context.Context) []Resource {
+	kinesisResource := MakeResource("mykin", nil, &kinesis{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
+	dynamoResource := MakeResource("mydyn", nil, &dynamo{ctxt: ctxt}, func(x interface{}) (string, error) { return "", errors.New("throttled") }, func(x interface{}) error { return nil })
+	deploymentResource := MakeResource("mydep1", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}, {FromResource: "mydyn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil })
+
+	return []Resource{kinesisResource, dynamoResource, deploymentResource}
+}
+
+func TestExportDOT(t *testing.T) {
+	ctxt := context.Background()
+	resources := exportResources(ctxt)
+
+	lib := New(&Opts{CustomLogger: t.Log, ContinueOnError: true})
+
+	var buf bytes.Buffer
+	if err := lib.ExportDOT(&buf, resources); err != nil {
+		t.Fatalf("unable to export %v", err)
+	}
+
+	if !strings.Contains(buf.String(), `"mykin" -> "mydep1" [label="Arn -> KinesisArn"];`) || !strings.Contains(buf.String(), `"mydyn" -> "mydep1";`) {
+		t.Fatalf("missing edges in\n%s", buf.String())
+	}
+	if strings.Contains(buf.String(), "fillcolor") {
+		t.Fatalf("expected no colours before a sync\n%s", buf.String())
+	}
+
+	lib.Sync(ctxt, resources, false)
+
+	buf.Reset()
+	if err := lib.ExportDOT(&buf, resources); err != nil {
+		t.Fatalf("unable to export %v", err)
+	}
+
+	t.Logf("dot:\n%s", buf.String())
+
+	for _, node := range []string{`"mykin" [style=filled, fillcolor=palegreen];`, `"mydyn" [style=filled, fillcolor=salmon];`, `"mydep1" [style=filled, fillcolor=lightgrey];`} {
+		if !strings.Contains(buf.String(), node) {
+			t.Fatalf("missing %s in\n%s", node, buf.String())
+		}
+	}
+
+	// only the resources of the last Sync are coloured
+	lib.Sync(ctxt, resources[:1], false)
+
+	buf.Reset()
+	if err := lib.ExportDOT(&buf, resources); err != nil {
+		t.Fatalf("unable to export %v", err)
+	}
+
+	if !strings.Contains(buf.String(), `"mykin" [style=filled, fillcolor=palegreen];`) || strings.Count(buf.String(), "fillcolor") != 1 {
+		t.Fatalf("expected only mykin to be coloured in\n%s", buf.String())
+	}
+}
+
+func TestExportMermaid(t *testing.T) {
+	ctxt := context.Background()
+	resources := exportResources(ctxt)
+
+	lib := New(&Opts{CustomLogger: t.Log, ContinueOnError: true})
+	lib.Sync(ctxt, resources, false)
+
+	var buf bytes.Buffer
+	if err := lib.ExportMermaid(&buf, resources); err != nil {
+		t.Fatalf("unable to export %v", err)
+	}
+
+	t.Logf("mermaid:\n%s", buf.String())
+
+	for _, line := range []string{`n0["mykin"]`, `n0 -->|"Arn -> KinesisArn"| n2`, `n1 --> n2`, `class n1 failed`} {
+		if !strings.Contains(buf.String(), line) {
+			t.Fatalf("missing %s in\n%s", line, buf.String())
+		}
+	}
+}
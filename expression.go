@@ -0,0 +1,192 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// reference is a field of a resource read by an expression, field is empty when
+// the expression reads the whole resource
+type reference struct {
+	resource string
+	field    string
+}
+
+// parseExpression parses the text/template of a Dependency. The template data
+// maps resource names to the structs implementing them, so {{.mykin.Arn}} reads
+// the Arn field of mykin.
+func parseExpression(dep Dependency) (*template.Template, error) {
+	return template.New(dep.ToField).Option("missingkey=error").Parse(dep.Expression)
+}
+
+// references lists the resource fields read by a parsed expression. Within
+// range and with blocks dot is no longer the template data, those blocks may
+// only reach resources through $.
+func references(t *template.Template) []reference {
+	refs := []reference{}
+
+	field := func(ident []string) {
+		if len(ident) > 0 {
+			refs = append(refs, reference{ident[0], strings.Join(ident[1:], ".")})
+		}
+	}
+
+	var walk func(n parse.Node, root bool)
+	walk = func(n parse.Node, root bool) {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Nodes {
+				walk(c, root)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, root)
+		case *parse.TemplateNode:
+			walk(n.Pipe, root)
+		case *parse.IfNode:
+			walk(n.Pipe, root)
+			walk(n.List, root)
+			walk(n.ElseList, root)
+		case *parse.RangeNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		case *parse.WithNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, c := range n.Cmds {
+				walk(c, root)
+			}
+		case *parse.CommandNode:
+			// index . "my-kin" reads resources whose names are not identifiers
+			if len(n.Args) > 2 && n.Args[0].String() == "index" {
+				_, dot := n.Args[1].(*parse.DotNode)
+				v, isVar := n.Args[1].(*parse.VariableNode)
+				if s, ok := n.Args[2].(*parse.StringNode); ok && (dot && root || isVar && v.String() == "$") {
+					refs = append(refs, reference{resource: s.Text})
+				}
+			}
+			for _, c := range n.Args {
+				walk(c, root)
+			}
+		case *parse.ChainNode:
+			walk(n.Node, root)
+		case *parse.FieldNode:
+			if root {
+				field(n.Ident)
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 0 && n.Ident[0] == "$" {
+				field(n.Ident[1:])
+			}
+		}
+	}
+
+	walk(t.Tree.Root, true)
+
+	return refs
+}
+
+// parents lists the resources a Dependency waits for, the FromResource along with
+// the resources read by its Expression. Expressions are validated by check.
+func parents(dep Dependency) []string {
+	names := []string{}
+	if len(dep.FromResource) > 0 {
+		names = append(names, dep.FromResource)
+	}
+
+	if len(dep.Expression) == 0 {
+		return names
+	}
+
+	t, err := parseExpression(dep)
+	if err != nil {
+		return names
+	}
+
+	seen := map[string]bool{dep.FromResource: true}
+	for _, ref := range references(t) {
+		if !seen[ref.resource] {
+			seen[ref.resource] = true
+			names = append(names, ref.resource)
+		}
+	}
+	return names
+}
+
+// checkExpression validates the Expression of a Dependency, the resources and fields
+// it reads must exist and its ToField must accept a string
+func checkExpression(r Resource, dep Dependency, cache map[string]Resource) error {
+	if len(dep.FromField) > 0 || len(dep.ToField) == 0 {
+		return fmt.Errorf("Resource %s incorrect specification of expression, set ToField without FromField", r.ResourceName())
+	}
+	if err := checkField(r, dep.ToField); err != nil {
+		return err
+	}
+
+	t, err := parseExpression(dep)
+	if err != nil {
+		return fmt.Errorf("Resource %s invalid expression: %v", r.ResourceName(), err)
+	}
+
+	if len(dep.FromResource) > 0 {
+		if _, ok := cache[dep.FromResource]; !ok {
+			return fmt.Errorf("Dependent resource %s doesn't exist", dep.FromResource)
+		}
+	}
+
+	for _, ref := range references(t) {
+		from, ok := cache[ref.resource]
+		if !ok {
+			return fmt.Errorf("Dependent resource %s doesn't exist", ref.resource)
+		}
+		if err := checkField(from, ref.field); err != nil {
+			return err
+		}
+	}
+
+	toType, _ := pathType(backingType(r), dep.ToField)
+	if !convertible(reflect.TypeOf(""), toType) {
+		return fmt.Errorf("Resource %s cannot inject expression into %s of type %s", r.ResourceName(), dep.ToField, toType)
+	}
+
+	return nil
+}
+
+// evaluate renders the Expression of a Dependency of a Resource into its ToField
+func evaluate(r Resource, dep Dependency, cache map[string]Resource) error {
+	t, err := parseExpression(dep)
+	if err != nil {
+		return err
+	}
+
+	data := map[string]interface{}{}
+	for _, name := range parents(dep) {
+		// pointers keep methods with pointer receivers available
+		if v := backing(cache[name]); v.CanAddr() {
+			data[name] = v.Addr().Interface()
+		} else if v.IsValid() {
+			data[name] = v.Interface()
+		}
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, data); err != nil {
+		return fmt.Errorf("unable to evaluate expression of %s: %v", r.ResourceName(), err)
+	}
+
+	if err := setPath(backing(r), dep.ToField, reflect.ValueOf(sb.String())); err != nil {
+		return fmt.Errorf("unable to inject expression into %s of %s: %v", dep.ToField, r.ResourceName(), err)
+	}
+	return nil
+}
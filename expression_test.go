@@ -0,0 +1,94 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type stream struct {
+	Region     string
+	StreamName string
+}
+
+func TestReferences(t *testing.T) {
+	for expr, expected := range map[string][]reference{
+		"arn:{{.mykin.Region}}:{{.mykin.StreamName}}":             {{"mykin", "Region"}, {"mykin", "StreamName"}},
+		`{{index . "my-kin"}}{{if .mydyn}}{{$.mydep.Arn}}{{end}}`: {{resource: "my-kin"}, {"mydyn", ""}, {"mydep", "Arn"}},
+		"{{with .mykin}}{{.Region}}{{end}}":                       {{"mykin", ""}},
+		`{{range .mykin.Tags}}{{printf "%s" .}}{{end}}`:           {{"mykin", "Tags"}},
+	} {
+		tmpl, err := parseExpression(Dependency{ToField: "Arn", Expression: expr})
+		if err != nil {
+			t.Fatalf("unable to parse %s: %v", expr, err)
+		}
+		if refs := references(tmpl); !reflect.DeepEqual(refs, expected) {
+			t.Fatalf("expected %s to reference %v, got %v", expr, expected, refs)
+		}
+	}
+}
+
+func TestSyncExpression(t *testing.T) {
+	ctxt := context.Background()
+
+	ok := func(x interface{}) (string, error) { return "", nil }
+	del := func(x interface{}) error { return nil }
+	set := func(x interface{}) (string, error) { x.(*stream).Region = "us-west-2"; return "", nil }
+
+	expr := "arn:aws:kinesis:{{.mykin.Region}}:{{.mykin.StreamName}}/{{.mydyn.StreamName}}"
+
+	resources := []Resource{
+		MakeResource("mydep", []Dependency{{ToField: "KinesisArn", Expression: expr}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { return x.(*deployment).KinesisArn, nil }, del),
+		MakeResource("mykin", nil, &stream{StreamName: "events"}, set, del),
+		MakeResource("mydyn", nil, &stream{StreamName: "table"}, ok, del),
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	plan, err := lib.Plan(ctxt, resources, false)
+	if err != nil {
+		t.Fatalf("unable to plan %v", err)
+	}
+	if len(plan.Waves) != 2 || !reflect.DeepEqual(plan.Waves[1], []string{"mydep"}) || plan.Injections[0].Expression != expr {
+		t.Fatalf("expected mydep to wait for the resources of its expression, got %v", plan)
+	}
+
+	status, err := lib.Sync(ctxt, resources, false)
+	if err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+
+	if status["mydep"] != "arn:aws:kinesis:us-west-2:events/table" {
+		t.Fatalf("unexpected status %v", status)
+	}
+}
+
+func TestCheckExpression(t *testing.T) {
+	ctxt := context.Background()
+
+	ok := func(x interface{}) (string, error) { return "", nil }
+	del := func(x interface{}) error { return nil }
+
+	for expected, dep := range map[string]Dependency{
+		"Dependent resource mydyn doesn't exist":            {ToField: "KinesisArn", Expression: "{{.mydyn.Region}}"},
+		"in mykin embedded Resource did not find field Bad": {ToField: "KinesisArn", Expression: "{{.mykin.Bad}}"},
+		"invalid expression":                                {ToField: "KinesisArn", Expression: "{{.mykin.Region"},
+		"set ToField without FromField":                     {FromResource: "mykin", FromField: "Region", ToField: "KinesisArn", Expression: "{{.mykin.Region}}"},
+		"cannot inject expression into Port of type int64":  {ToField: "Port", Expression: "{{.mykin.Region}}"},
+	} {
+		to := interface{}(&deployment{ctxt: ctxt})
+		if dep.ToField == "Port" {
+			to = &service{}
+		}
+
+		resources := []Resource{
+			MakeResource("mykin", nil, &stream{}, ok, del),
+			MakeResource("mydep", []Dependency{dep}, to, ok, del),
+		}
+
+		if err := check(resources); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q, got %v", expected, err)
+		}
+	}
+}
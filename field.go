@@ -0,0 +1,318 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// step is a single element of a field path, either a struct field or an index
+// into a slice, array or map
+type step struct {
+	name  string
+	index bool
+}
+
+// parsePath splits a field path like Status.Ports[0].Name or Tags[env] in steps
+func parsePath(path string) ([]step, error) {
+	steps := []step{}
+
+	for _, part := range strings.Split(path, ".") {
+		name := part
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name = part[:i]
+		}
+		if len(name) == 0 {
+			return nil, fmt.Errorf("invalid field path %s", path)
+		}
+		steps = append(steps, step{name: name})
+
+		for rest := part[len(name):]; len(rest) > 0; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 2 {
+				return nil, fmt.Errorf("invalid field path %s", path)
+			}
+			steps = append(steps, step{name: rest[1:end], index: true})
+			rest = rest[end+1:]
+		}
+	}
+
+	return steps, nil
+}
+
+// pathType returns the type of the value addressed by path in a struct of type t
+func pathType(t reflect.Type, path string) (reflect.Type, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if t == nil {
+		return nil, fmt.Errorf("did not find field %s, there is no struct", path)
+	}
+
+	for _, s := range steps {
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+
+		switch {
+		case !s.index && t.Kind() == reflect.Struct:
+			f, found := t.FieldByName(s.name)
+			if !found {
+				return nil, fmt.Errorf("did not find field %s", path)
+			}
+			if len(f.PkgPath) > 0 {
+				return nil, fmt.Errorf("field %s is unexported", path)
+			}
+			t = f.Type
+		case s.index && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array):
+			if _, err := strconv.Atoi(s.name); err != nil {
+				return nil, fmt.Errorf("invalid index %s in field %s", s.name, path)
+			}
+			t = t.Elem()
+		case s.index && t.Kind() == reflect.Map:
+			if _, err := mapKey(t.Key(), s.name); err != nil {
+				return nil, fmt.Errorf("invalid key %s in field %s: %v", s.name, path, err)
+			}
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("cannot address %s of %s in field %s", s.name, t, path)
+		}
+	}
+
+	return t, nil
+}
+
+// getPath reads the value addressed by path in v
+func getPath(v reflect.Value, path string) (reflect.Value, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	if !v.IsValid() {
+		return reflect.Value{}, fmt.Errorf("did not find field %s, there is no struct", path)
+	}
+
+	for _, s := range steps {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return reflect.Value{}, fmt.Errorf("nil value at %s in field %s", s.name, path)
+			}
+			v = v.Elem()
+		}
+
+		if v, err = child(v, s, false); err != nil {
+			return reflect.Value{}, fmt.Errorf("%v in field %s", err, path)
+		}
+	}
+
+	return v, nil
+}
+
+// setPath converts value to the type addressed by path in v and assigns it,
+// nil pointers and maps along the path are allocated
+func setPath(v reflect.Value, path string, value reflect.Value) error {
+	steps, err := parsePath(path)
+	if err != nil {
+		return err
+	}
+
+	if !v.IsValid() {
+		return fmt.Errorf("did not find field %s, there is no struct", path)
+	}
+
+	if err := set(v, steps, value); err != nil {
+		return fmt.Errorf("%v in field %s", err, path)
+	}
+	return nil
+}
+
+func set(v reflect.Value, steps []step, value reflect.Value) error {
+	if len(steps) == 0 {
+		c, err := convert(value, v.Type())
+		if err != nil {
+			return err
+		}
+		v.Set(c)
+		return nil
+	}
+
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	s := steps[0]
+
+	// map elements are not addressable, they are copied, updated and stored back
+	if s.index && v.Kind() == reflect.Map {
+		key, err := mapKey(v.Type().Key(), s.name)
+		if err != nil {
+			return err
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if existing := v.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := set(elem, steps[1:], value); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	}
+
+	next, err := child(v, s, true)
+	if err != nil {
+		return err
+	}
+	return set(next, steps[1:], value)
+}
+
+// child returns the struct field, slice or array element, or map element of v
+// named by s
+func child(v reflect.Value, s step, settable bool) (reflect.Value, error) {
+	switch {
+	case !s.index && v.Kind() == reflect.Struct:
+		f, found := v.Type().FieldByName(s.name)
+		if !found {
+			return reflect.Value{}, fmt.Errorf("did not find %s", s.name)
+		}
+		if len(f.PkgPath) > 0 {
+			return reflect.Value{}, fmt.Errorf("%s is unexported", s.name)
+		}
+		fv := v.FieldByIndex(f.Index)
+		if settable && !fv.CanSet() {
+			return reflect.Value{}, fmt.Errorf("%s cannot be set", s.name)
+		}
+		return fv, nil
+	case s.index && (v.Kind() == reflect.Slice || v.Kind() == reflect.Array):
+		i, err := strconv.Atoi(s.name)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("invalid index %s", s.name)
+		}
+		if i < 0 || i >= v.Len() {
+			return reflect.Value{}, fmt.Errorf("index %d out of range", i)
+		}
+		return v.Index(i), nil
+	case s.index && v.Kind() == reflect.Map:
+		key, err := mapKey(v.Type().Key(), s.name)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		e := v.MapIndex(key)
+		if !e.IsValid() {
+			return reflect.Value{}, fmt.Errorf("did not find key %s", s.name)
+		}
+		return e, nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot address %s of %s", s.name, v.Type())
+}
+
+// mapKey converts the text of an index to a key of type t
+func mapKey(t reflect.Type, key string) (reflect.Value, error) {
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(key).Convert(t), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(n).Convert(t), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(key, 10, t.Bits())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.ValueOf(n).Convert(t), nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("unsupported key type %s", t)
+}
+
+// convert returns v as a value of type t. Pointers and interfaces are followed,
+// a nil one converts to the zero value, and numbers are only widened.
+func convert(v reflect.Value, t reflect.Type) (reflect.Value, error) {
+	if v.Type().AssignableTo(t) {
+		return v, nil
+	}
+
+	switch {
+	case v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface:
+		if v.IsNil() {
+			return reflect.Zero(t), nil
+		}
+		return convert(v.Elem(), t)
+	case t.Kind() == reflect.Ptr:
+		c, err := convert(v, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		p := reflect.New(t.Elem())
+		p.Elem().Set(c)
+		return p, nil
+	case convertible(v.Type(), t):
+		return v.Convert(t), nil
+	}
+
+	return reflect.Value{}, fmt.Errorf("cannot convert %s to %s", v.Type(), t)
+}
+
+// convertible reports whether a value of type from converts to type to without
+// loss, following pointers. Only named types of the same kind and numeric
+// widening are allowed. The value held by an interface is only known when
+// converting, so interfaces are assumed to convert.
+func convertible(from, to reflect.Type) bool {
+	for from.Kind() == reflect.Ptr {
+		from = from.Elem()
+	}
+	for to.Kind() == reflect.Ptr {
+		to = to.Elem()
+	}
+
+	if from.AssignableTo(to) {
+		return true
+	}
+
+	switch {
+	case from.Kind() == reflect.Interface:
+		return true
+	case isInt(from) && isInt(to), isUint(from) && isUint(to), isFloat(from) && isFloat(to):
+		return from.Bits() <= to.Bits()
+	case isUint(from) && isInt(to):
+		return from.Bits() < to.Bits()
+	case from.Kind() == to.Kind():
+		return from.ConvertibleTo(to)
+	}
+
+	return false
+}
+
+func isInt(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUint(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+func isFloat(t reflect.Type) bool {
+	return t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+}
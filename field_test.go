@@ -0,0 +1,140 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type port struct {
+	Name   string
+	Number int32
+}
+
+type service struct {
+	Status struct {
+		Arn   *string
+		Ports []port
+	}
+	Tags    map[string]string
+	Port    int64
+	Pointer *int
+	Labels  map[int]*port
+	hidden  string
+}
+
+func TestParsePath(t *testing.T) {
+	steps, err := parsePath("Status.Ports[0].Name")
+	if err != nil {
+		t.Fatalf("unable to parse %v", err)
+	}
+
+	expected := []step{{name: "Status"}, {name: "Ports"}, {name: "0", index: true}, {name: "Name"}}
+	if !reflect.DeepEqual(steps, expected) {
+		t.Fatalf("expected %v, got %v", expected, steps)
+	}
+
+	for _, bad := range []string{"", "Status.", "Tags[]", "Tags[env", "Tags]env[", "[0]"} {
+		if _, err := parsePath(bad); err == nil {
+			t.Fatalf("expected %q to be rejected", bad)
+		}
+	}
+}
+
+func TestPathType(t *testing.T) {
+	typ := reflect.TypeOf(service{})
+
+	for path, expected := range map[string]reflect.Type{
+		"Status.Arn":           reflect.TypeOf((*string)(nil)),
+		"Status.Ports[1].Name": reflect.TypeOf(""),
+		"Tags[env]":            reflect.TypeOf(""),
+		"Labels[3].Number":     reflect.TypeOf(int32(0)),
+	} {
+		if found, err := pathType(typ, path); err != nil || found != expected {
+			t.Fatalf("expected %s to be %v, got %v, err = %v", path, expected, found, err)
+		}
+	}
+
+	for _, bad := range []string{"Bad", "hidden", "Tags.env", "Status.Ports[x]", "Labels[x]", "Port[0]"} {
+		if _, err := pathType(typ, bad); err == nil {
+			t.Fatalf("expected %s to be rejected", bad)
+		}
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	arn := "hello123"
+	s := &service{}
+	s.Status.Ports = []port{{Name: "http"}}
+	v := reflect.ValueOf(s).Elem()
+
+	values := map[string]interface{}{
+		"Tags[arn]":              &arn,
+		"Status.Arn":             arn,
+		"Status.Ports[0].Number": int8(80),
+		"Port":                   int32(443),
+		"Pointer":                3,
+		"Labels[2].Name":         "grpc",
+	}
+	for path, value := range values {
+		if err := setPath(v, path, reflect.ValueOf(value)); err != nil {
+			t.Fatalf("unable to set %s: %v", path, err)
+		}
+	}
+
+	if s.Tags["arn"] != arn || *s.Status.Arn != arn || s.Status.Ports[0].Number != 80 || s.Port != 443 || *s.Pointer != 3 || s.Labels[2].Name != "grpc" {
+		t.Fatalf("unexpected service %+v", s)
+	}
+
+	if found, err := getPath(v, "Labels[2].Name"); err != nil || found.Interface() != "grpc" {
+		t.Fatalf("expected grpc, got %v, err = %v", found, err)
+	}
+
+	for path, value := range map[string]interface{}{
+		"Status.Ports[0].Number": int64(80),
+		"Status.Ports[3].Name":   "http",
+		"Tags[env]":              5,
+		"hidden":                 "secret",
+	} {
+		if err := setPath(v, path, reflect.ValueOf(value)); err == nil {
+			t.Fatalf("expected setting %s to %v to fail", path, value)
+		}
+	}
+}
+
+func TestSyncNestedFields(t *testing.T) {
+	ctxt := context.Background()
+
+	arn := "hello123"
+	svc := &service{}
+	svc.Status.Arn = &arn
+	svc.Status.Ports = []port{{Name: "http", Number: 8080}}
+
+	ok := func(x interface{}) (string, error) { return "", nil }
+	del := func(x interface{}) error { return nil }
+
+	resources := []Resource{
+		MakeResource("mysvc", nil, svc, ok, del),
+		MakeResource("mydep", []Dependency{{FromResource: "mysvc", FromField: "Status.Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { return x.(*deployment).KinesisArn, nil }, del),
+		MakeResource("mytag", []Dependency{{FromResource: "mysvc", FromField: "Status.Ports[0].Number", ToField: "Port"}, {FromResource: "mysvc", FromField: "Status.Arn", ToField: "Tags[arn]"}}, &service{}, func(x interface{}) (string, error) { s := x.(*service); return s.Tags["arn"], nil }, del),
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	status, err := lib.Sync(ctxt, resources, false)
+	if err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+
+	if status["mydep"] != arn || status["mytag"] != arn || resources[2].(*protoBuilder).UDef.(*service).Port != 8080 {
+		t.Fatalf("unexpected status %v", status)
+	}
+
+	svc.Status.Ports = nil
+
+	_, err = lib.Sync(ctxt, resources, false)
+	if em, ok := err.(ErrorMapper); !ok || !strings.Contains(em.ErrorMap()["mytag"].Error(), "index 0 out of range") {
+		t.Fatalf("expected an injection error, got %v", err)
+	}
+}
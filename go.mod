@@ -1,5 +1,7 @@
 module github.com/srohatgi/graph
 
+go 1.20
+
 require (
 	github.com/imdario/mergo v0.3.7
 	gopkg.in/yaml.v2 v2.2.2
@@ -8,7 +8,13 @@
 // service. Resources may have a Dependency order of creation and deletion.
 // Idiomatic resources may have a single backing structure for fulfilling the
 // interface. The library utilizes this backing structure to locate and inject
-// public properties of a resource during execution.
+// public properties of a resource during execution. Besides ResourceDependencies,
+// fields of the backing structure may declare a Dependency with a struct tag:
+//
+//  KinesisArn string `graph:"from=mykin.Arn"`
+//
+// Graph is a generic directed graph, it provides the algorithms the library
+// uses for ordering resources to any comparable vertex type.
 //
 // Functions
 //
@@ -17,6 +23,10 @@
 //
 //  status, err := Sync(resources, false) // refer to signature below
 //
+// The Plan() function returns the order in which Sync() would process resources,
+// without updating or deleting any of them. The SyncResults() function works
+// like Sync(), returning a structured Result for every resource.
+//
 // The library tries to execute multiple resources concurrently. There is a handy
 // ErrorMapper interface that allows developers to query resource specific errors.
 //
@@ -35,12 +45,41 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Opts captures customizable functionality like logging
 type Opts struct {
 	CustomLogger func(args ...interface{})
 	Decorator    func(r Resource) Resource
+	// MaxConcurrency limits the number of resources processed at the same time,
+	// zero means no limit.
+	MaxConcurrency int
+	// ContinueOnError keeps creating every resource whose dependencies succeeded
+	// after a failure. Resources depending on a failed resource are reported
+	// with a SkippedError.
+	ContinueOnError bool
+	// RetryPolicy applies to every Resource that does not implement Retrier,
+	// a failing Update is not retried when nil.
+	RetryPolicy *RetryPolicy
+	// StateStore records the outcome of every successful Update, no state is
+	// kept when nil.
+	StateStore StateStore
+	// SkipUnchanged skips the Update of a Resource whose exported fields, including
+	// values injected from dependencies, match the last successful Update recorded
+	// in the StateStore. It requires a StateStore, Sync fails without one.
+	SkipUnchanged bool
+	// Ordering breaks ties between resources ready at the same time, it defaults
+	// to OrderByReadiness.
+	Ordering Ordering
+	// Rollback makes a Sync transactional: when creation fails, resources updated
+	// by the Sync are rolled back in reverse order, see Rollbacker.
+	Rollback bool
+	// Timeout bounds the processing of every Resource that does not implement
+	// Timeouter, zero means no timeout.
+	Timeout time.Duration
 }
 
 // New creates an instance object
@@ -48,6 +87,9 @@ func New(opts *Opts) *Lib {
 	lib := &Lib{
 		logger:    func(args ...interface{}) {},
 		decorator: func(r Resource) Resource { return r },
+		outcomes:  map[string]outcome{},
+		updates:   map[string]time.Duration{},
+		deletes:   map[string]time.Duration{},
 	}
 	if opts != nil && opts.CustomLogger != nil {
 		lib.logger = opts.CustomLogger
@@ -57,13 +99,38 @@ func New(opts *Opts) *Lib {
 		lib.decorator = opts.Decorator
 	}
 
+	if opts != nil {
+		lib.maxConcurrency = opts.MaxConcurrency
+		lib.continueOnError = opts.ContinueOnError
+		lib.retryPolicy = opts.RetryPolicy
+		lib.store = opts.StateStore
+		lib.skipUnchanged = opts.SkipUnchanged
+		lib.ordering = opts.Ordering
+		lib.rollback = opts.Rollback
+		lib.timeout = opts.Timeout
+	}
+
 	return lib
 }
 
 // Lib object is required for using the library
 type Lib struct {
-	logger    func(args ...interface{})
-	decorator func(r Resource) Resource
+	logger          func(args ...interface{})
+	decorator       func(r Resource) Resource
+	maxConcurrency  int
+	continueOnError bool
+	retryPolicy     *RetryPolicy
+	store           StateStore
+	skipUnchanged   bool
+	ordering        Ordering
+	rollback        bool
+	timeout         time.Duration
+
+	// mux guards the outcomes and durations of the last Sync
+	mux      sync.Mutex
+	outcomes map[string]outcome
+	updates  map[string]time.Duration
+	deletes  map[string]time.Duration
 }
 
 // graph data type
@@ -133,7 +200,213 @@ func (g *graph) addEdge(v1, w1 int) {
 	g.adj[v1] = append(g.adj[v1], w1)
 }
 
+// transpose returns a graph with every edge reversed
+func (g *graph) transpose() *graph {
+	t := newGraph(g.v)
+	for v := 0; v < g.v; v++ {
+		for _, w := range g.adj[v] {
+			t.addEdge(w, v)
+		}
+	}
+	return t
+}
+
+// induced returns the subgraph of the kept vertices, numbered in order, along with
+// the vertex of g every vertex of the subgraph stands for
+func (g *graph) induced(keep []bool) (*graph, []int) {
+	kept := []int{}
+	renumbered := make([]int, g.v)
+	for v := 0; v < g.v; v++ {
+		if keep[v] {
+			renumbered[v] = len(kept)
+			kept = append(kept, v)
+		}
+	}
+
+	sub := newGraph(len(kept))
+	for _, v := range kept {
+		for _, w := range g.adj[v] {
+			if keep[w] {
+				sub.addEdge(renumbered[v], renumbered[w])
+			}
+		}
+	}
+
+	return sub, kept
+}
+
+// removeEdge (v1, w1)
+func (g *graph) removeEdge(v1, w1 int) bool {
+	for i, w := range g.adj[v1] {
+		if w == w1 {
+			g.adj[v1] = append(g.adj[v1][:i], g.adj[v1][i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
 // String representation
 func (g *graph) String() string {
 	return fmt.Sprintf("v=%v, adj=%v", g.v, g.adj)
 }
+
+// Graph is a directed graph of comparable vertices, e.g. resource names. Vertices
+// are kept in the order they were added. Adding a vertex or an edge takes constant
+// time. A Graph is not safe for concurrent use.
+type Graph[K comparable] struct {
+	keys  []K
+	index map[K]int
+	g     *graph
+	// edges holds the edges of g, so that AddEdge does not scan adjacency lists
+	edges map[[2]int]bool
+}
+
+// NewGraph creates an empty Graph
+func NewGraph[K comparable]() *Graph[K] {
+	return &Graph[K]{index: map[K]int{}, g: newGraph(0), edges: map[[2]int]bool{}}
+}
+
+// AddVertex adds k to the graph, it returns false if k already exists
+func (gr *Graph[K]) AddVertex(k K) bool {
+	if _, found := gr.index[k]; found {
+		return false
+	}
+
+	gr.index[k] = len(gr.keys)
+	gr.keys = append(gr.keys, k)
+	gr.g.v++
+	gr.g.adj = append(gr.g.adj, nil)
+	return true
+}
+
+// RemoveVertex removes k and all of its edges, it returns false if k does not exist
+func (gr *Graph[K]) RemoveVertex(k K) bool {
+	v, found := gr.index[k]
+	if !found {
+		return false
+	}
+
+	// vertices after v shift down by one
+	shift := func(w int) int {
+		if w > v {
+			return w - 1
+		}
+		return w
+	}
+
+	g := newGraph(gr.g.v - 1)
+	edges := map[[2]int]bool{}
+	for w := 0; w < gr.g.v; w++ {
+		if w == v {
+			continue
+		}
+		for _, x := range gr.g.adj[w] {
+			if x != v {
+				g.addEdge(shift(w), shift(x))
+				edges[[2]int{shift(w), shift(x)}] = true
+			}
+		}
+	}
+
+	gr.keys = append(gr.keys[:v], gr.keys[v+1:]...)
+	delete(gr.index, k)
+	for i := v; i < len(gr.keys); i++ {
+		gr.index[gr.keys[i]] = i
+	}
+	gr.g = g
+	gr.edges = edges
+	return true
+}
+
+// HasVertex reports whether k is in the graph
+func (gr *Graph[K]) HasVertex(k K) bool {
+	_, found := gr.index[k]
+	return found
+}
+
+// AddEdge adds an edge from -> to, adding missing vertices. It returns false
+// if the edge already exists.
+func (gr *Graph[K]) AddEdge(from, to K) bool {
+	if gr.HasEdge(from, to) {
+		return false
+	}
+
+	gr.AddVertex(from)
+	gr.AddVertex(to)
+	v, w := gr.index[from], gr.index[to]
+	gr.g.addEdge(v, w)
+	gr.edges[[2]int{v, w}] = true
+	return true
+}
+
+// RemoveEdge removes the edge from -> to, it returns false if the edge does not exist
+func (gr *Graph[K]) RemoveEdge(from, to K) bool {
+	v, vFound := gr.index[from]
+	w, wFound := gr.index[to]
+	if !vFound || !wFound {
+		return false
+	}
+	delete(gr.edges, [2]int{v, w})
+	return gr.g.removeEdge(v, w)
+}
+
+// HasEdge reports whether the edge from -> to is in the graph
+func (gr *Graph[K]) HasEdge(from, to K) bool {
+	v, vFound := gr.index[from]
+	w, wFound := gr.index[to]
+	if !vFound || !wFound {
+		return false
+	}
+	return gr.edges[[2]int{v, w}]
+}
+
+// Vertices in the order they were added
+func (gr *Graph[K]) Vertices() []K {
+	return append([]K{}, gr.keys...)
+}
+
+// Successors are the vertices k has an edge to
+func (gr *Graph[K]) Successors(k K) []K {
+	v, found := gr.index[k]
+	if !found {
+		return nil
+	}
+	return gr.keysOf(gr.g.adjascent(v))
+}
+
+// Predecessors are the vertices that have an edge to k
+func (gr *Graph[K]) Predecessors(k K) []K {
+	w, found := gr.index[k]
+	if !found {
+		return nil
+	}
+
+	preds := []K{}
+	for v := 0; v < gr.g.vertices(); v++ {
+		for _, x := range gr.g.adjascent(v) {
+			if x == w {
+				preds = append(preds, gr.keys[v])
+				break
+			}
+		}
+	}
+	return preds
+}
+
+// String representation
+func (gr *Graph[K]) String() string {
+	var sb strings.Builder
+	for v, k := range gr.keys {
+		fmt.Fprintf(&sb, "%v -> %v\n", k, gr.keysOf(gr.g.adjascent(v)))
+	}
+	return sb.String()
+}
+
+func (gr *Graph[K]) keysOf(vertices []int) []K {
+	keys := make([]K, len(vertices))
+	for i, v := range vertices {
+		keys[i] = gr.keys[v]
+	}
+	return keys
+}
@@ -1,6 +1,7 @@
 package graph
 
 import (
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -41,3 +42,42 @@ func TestFromReader(t *testing.T) {
 
 	t.Logf("graph = %v\n", g)
 }
+
+func TestGraph(t *testing.T) {
+	g := NewGraph[string]()
+
+	g.AddEdge("mykin", "mydep")
+	g.AddEdge("mydyn", "mydep")
+	g.AddEdge("mykin", "myapi")
+	g.AddVertex("mylog")
+
+	if g.AddEdge("mykin", "mydep") || g.AddVertex("mykin") {
+		t.Fatal("expected duplicate edges and vertices to be ignored")
+	}
+
+	if !reflect.DeepEqual(g.Vertices(), []string{"mykin", "mydep", "mydyn", "myapi", "mylog"}) {
+		t.Fatalf("unexpected vertices %v", g.Vertices())
+	}
+
+	if !reflect.DeepEqual(g.Successors("mykin"), []string{"mydep", "myapi"}) {
+		t.Fatalf("unexpected successors %v", g.Successors("mykin"))
+	}
+
+	if !reflect.DeepEqual(g.Predecessors("mydep"), []string{"mykin", "mydyn"}) {
+		t.Fatalf("unexpected predecessors %v", g.Predecessors("mydep"))
+	}
+
+	if !g.RemoveVertex("mydyn") || g.HasVertex("mydyn") {
+		t.Fatal("expected mydyn to be removed")
+	}
+
+	if !g.HasEdge("mykin", "myapi") || !reflect.DeepEqual(g.Predecessors("mydep"), []string{"mykin"}) {
+		t.Fatalf("expected edges to survive vertex removal, graph:\n%v", g)
+	}
+
+	if !g.RemoveEdge("mykin", "mydep") || g.HasEdge("mykin", "mydep") || g.RemoveEdge("mykin", "mydep") {
+		t.Fatal("expected edge mykin -> mydep to be removed once")
+	}
+
+	t.Logf("graph:\n%v", g)
+}
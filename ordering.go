@@ -0,0 +1,50 @@
+package graph
+
+// Ordering breaks ties between resources that are ready to be processed at the
+// same time, making the order of a Sync reproducible.
+type Ordering int
+
+const (
+	// OrderByReadiness processes resources in the order they become ready, starting
+	// with the order of the Resource slice. It is the default, and the only Ordering
+	// scheduling resources in linear time when none implements Prioritizer.
+	OrderByReadiness Ordering = iota
+	// OrderByInput processes resources in the order of the Resource slice.
+	OrderByInput
+	// OrderByName processes resources in lexical order of their names.
+	OrderByName
+)
+
+// Prioritizer may be implemented by a Resource to be processed before other
+// resources that are ready at the same time. Resources with a higher priority
+// come first, resources not implementing Prioritizer have a priority of zero.
+type Prioritizer interface {
+	ResourcePriority() int
+}
+
+// order returns the tie breaker of resources by priority then Ordering, it is nil
+// when resources are ordered by readiness and none implements Prioritizer
+func (lib *Lib) order(resources []Resource) func(v, w int) bool {
+	prioritized := false
+	priority := make([]int, len(resources))
+	for i, r := range resources {
+		if p, ok := r.(Prioritizer); ok {
+			priority[i] = p.ResourcePriority()
+			prioritized = true
+		}
+	}
+
+	if lib.ordering == OrderByReadiness && !prioritized {
+		return nil
+	}
+
+	return func(v, w int) bool {
+		if priority[v] != priority[w] {
+			return priority[v] > priority[w]
+		}
+		if lib.ordering == OrderByName && resources[v].ResourceName() != resources[w].ResourceName() {
+			return resources[v].ResourceName() < resources[w].ResourceName()
+		}
+		return v < w
+	}
+}
@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+type prioritized struct {
+	Depends
+	priority int
+	ran      func(name string)
+}
+
+func (p *prioritized) Update(ctxt context.Context) (string, error) {
+	p.ran(p.Name)
+	return "", nil
+}
+
+func (p *prioritized) Delete(ctxt context.Context) error {
+	p.ran(p.Name)
+	return nil
+}
+
+func (p *prioritized) ResourcePriority() int { return p.priority }
+
+func orderedResources(ran func(string)) []Resource {
+	return []Resource{
+		&prioritized{Depends: Depends{Name: "mydep", Dependencies: []Dependency{{FromResource: "mykin"}}}, ran: ran},
+		&prioritized{Depends: Depends{Name: "mykin"}, ran: ran},
+		&prioritized{Depends: Depends{Name: "mydyn"}, ran: ran},
+		&prioritized{Depends: Depends{Name: "myalarm"}, priority: 1, ran: ran},
+		&prioritized{Depends: Depends{Name: "mycache"}, ran: ran},
+	}
+}
+
+func TestOrdering(t *testing.T) {
+	ctxt := context.Background()
+
+	for _, tc := range []struct {
+		ordering Ordering
+		expected []string
+	}{
+		{OrderByInput, []string{"myalarm", "mykin", "mydep", "mydyn", "mycache"}},
+		{OrderByName, []string{"myalarm", "mycache", "mydyn", "mykin", "mydep"}},
+	} {
+		var mux sync.Mutex
+		ran := []string{}
+		resources := orderedResources(func(name string) {
+			mux.Lock()
+			defer mux.Unlock()
+			ran = append(ran, name)
+		})
+
+		lib := New(&Opts{CustomLogger: t.Log, MaxConcurrency: 1, Ordering: tc.ordering})
+
+		if _, err := lib.Sync(ctxt, resources, false); err != nil {
+			t.Fatalf("unable to sync %v", err)
+		}
+
+		if !reflect.DeepEqual(ran, tc.expected) {
+			t.Fatalf("ordering %v: expected %v, ran %v", tc.ordering, tc.expected, ran)
+		}
+	}
+}
+
+func TestPlanGolden(t *testing.T) {
+	ctxt := context.Background()
+
+	lib := New(&Opts{CustomLogger: t.Log, Ordering: OrderByName})
+
+	golden := "wave 1: myalarm, mycache, mydyn, mykin\nwave 2: mydep\n"
+
+	for i := 0; i < 10; i++ {
+		plan, err := lib.Plan(ctxt, orderedResources(nil), false)
+		if err != nil {
+			t.Fatalf("unable to plan %v", err)
+		}
+
+		if plan.String() != golden {
+			t.Fatalf("expected plan\n%s\ngot\n%s", golden, plan)
+		}
+	}
+}
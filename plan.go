@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Plan describes the work a Sync would perform, without calling Update or Delete.
+type Plan struct {
+	// Waves lists, in order of execution, the resources that would be
+	// processed concurrently.
+	Waves [][]string
+	// Injections lists the field values copied between resources before Update.
+	Injections []Injection
+	// Deletes lists resources in order of deletion, it is only populated
+	// when planning a delete.
+	Deletes []string
+}
+
+// Injection describes a value copied from FromResource.FromField into
+// Resource.ToField, or the Expression rendered into it.
+type Injection struct {
+	Resource     string
+	FromResource string
+	FromField    string
+	ToField      string
+	Expression   string
+}
+
+func (in Injection) String() string {
+	if len(in.Expression) > 0 {
+		return fmt.Sprintf("%q -> %s.%s", in.Expression, in.Resource, in.ToField)
+	}
+	return fmt.Sprintf("%s.%s -> %s.%s", in.FromResource, in.FromField, in.Resource, in.ToField)
+}
+
+// String renders the plan in a form suitable for code reviews
+func (p *Plan) String() string {
+	var sb strings.Builder
+	for i, wave := range p.Waves {
+		fmt.Fprintf(&sb, "wave %d: %s\n", i+1, strings.Join(wave, ", "))
+	}
+	for _, in := range p.Injections {
+		fmt.Fprintf(&sb, "inject %v\n", in)
+	}
+	if len(p.Deletes) > 0 {
+		fmt.Fprintf(&sb, "delete order: %s\n", strings.Join(p.Deletes, ", "))
+	}
+	return sb.String()
+}
+
+// Plan validates the Resource slice exactly like Sync, and returns the execution plan
+// Sync would follow for the given value of toDelete. No resource is updated or deleted.
+func (lib *Lib) Plan(ctxt context.Context, resources []Resource, toDelete bool) (*Plan, error) {
+	g, err := prepare(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	lib.logger("starting plan")
+
+	plan := &Plan{}
+	less := lib.order(resources)
+
+	if toDelete {
+		rg := g.transpose()
+		for _, wave := range levels(rg, sortBy(rg, less)) {
+			names := []string{}
+			for _, i := range wave {
+				names = append(names, resources[i].ResourceName())
+			}
+			plan.Waves = append(plan.Waves, names)
+			plan.Deletes = append(plan.Deletes, names...)
+		}
+		return plan, nil
+	}
+
+	ordered := sortBy(g, less)
+	for _, wave := range levels(g, ordered) {
+		names := []string{}
+		for _, i := range wave {
+			names = append(names, resources[i].ResourceName())
+		}
+		plan.Waves = append(plan.Waves, names)
+	}
+
+	index := indexes(resources)
+	for _, i := range ordered {
+		for _, dep := range dependencies(resources[i], index) {
+			if len(dep.FromField) == 0 && len(dep.Expression) == 0 {
+				continue
+			}
+			plan.Injections = append(plan.Injections, Injection{
+				Resource:     resources[i].ResourceName(),
+				FromResource: dep.FromResource,
+				FromField:    dep.FromField,
+				ToField:      dep.ToField,
+				Expression:   dep.Expression,
+			})
+		}
+	}
+
+	return plan, nil
+}
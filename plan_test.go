@@ -0,0 +1,51 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestPlan(t *testing.T) {
+	ctxt := context.Background()
+
+	updated := false
+	update := func(x interface{}) (string, error) { updated = true; return "", nil }
+	del := func(x interface{}) error { updated = true; return nil }
+
+	kinesisResource := MakeResource("mykin", nil, &kinesis{ctxt: ctxt}, update, del)
+	dynamoResource := MakeResource("mydyn", nil, &dynamo{ctxt: ctxt}, update, del)
+	deploymentResource := MakeResource("mydep1", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}, {FromResource: "mydyn"}}, &deployment{ctxt: ctxt}, update, del)
+
+	resources := []Resource{deploymentResource, kinesisResource, dynamoResource}
+
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	plan, err := lib.Plan(ctxt, resources, false)
+	if err != nil {
+		t.Fatalf("unable to plan %v", err)
+	}
+
+	t.Logf("plan:\n%v", plan)
+
+	if len(plan.Waves) != 2 || len(plan.Waves[0]) != 2 || !reflect.DeepEqual(plan.Waves[1], []string{"mydep1"}) {
+		t.Fatalf("unexpected waves %v", plan.Waves)
+	}
+
+	if !reflect.DeepEqual(plan.Injections, []Injection{{Resource: "mydep1", FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}) {
+		t.Fatalf("unexpected injections %v", plan.Injections)
+	}
+
+	plan, err = lib.Plan(ctxt, resources, true)
+	if err != nil {
+		t.Fatalf("unable to plan delete %v", err)
+	}
+
+	if len(plan.Waves) != 2 || len(plan.Deletes) != 3 || plan.Deletes[0] != "mydep1" {
+		t.Fatalf("expected mydep1 to be deleted first, got %v", plan.Deletes)
+	}
+
+	if updated {
+		t.Fatal("plan must not call Update or Delete")
+	}
+}
@@ -0,0 +1,82 @@
+package graph
+
+import "context"
+
+// Action describes the change an Update made to a Resource
+type Action string
+
+// Actions reported in a Result
+const (
+	Created   Action = "created"
+	Updated   Action = "updated"
+	Unchanged Action = "unchanged"
+)
+
+// Result is the structured outcome of updating a Resource.
+type Result struct {
+	// Status is the message returned by the Resource.
+	Status string
+	// Outputs holds values produced by the Resource, keyed by name.
+	Outputs map[string]interface{}
+	// Action is the change made by the Update.
+	Action Action
+	// Warnings are problems that did not fail the Update.
+	Warnings []string
+}
+
+// UpdateResult may be implemented by a Resource to return a structured Result,
+// it is called in place of Update. It is looked up on the Resource returned by
+// Opts.Decorator, and on the Resource itself when the decorator hides it.
+// Resources only implementing Update report their status string with the
+// Updated action.
+type UpdateResult interface {
+	UpdateResult(ctxt context.Context) (Result, error)
+}
+
+// SyncResults works like Sync, but returns the Result of every updated resource
+// keyed by resource name. Resources skipped because they did not change since
+// the last Sync report the Unchanged action.
+func (lib *Lib) SyncResults(ctxt context.Context, resources []Resource, toDelete bool) (map[string]Result, error) {
+	g, err := prepare(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	return lib.sync(ctxt, resources, g, toDelete)
+}
+
+// update calls UpdateResult when implemented by the decorated Resource or by r,
+// and Update of the decorated Resource otherwise
+func (lib *Lib) update(ctxt context.Context, r Resource) (Result, error) {
+	d := lib.decorator(r)
+
+	ur, ok := d.(UpdateResult)
+	if !ok {
+		ur, ok = r.(UpdateResult)
+	}
+	if ok {
+		res, err := ur.UpdateResult(ctxt)
+		if len(res.Action) == 0 {
+			res.Action = Updated
+		}
+		return res, err
+	}
+
+	status, err := d.Update(ctxt)
+	return Result{Status: status, Action: Updated}, err
+}
+
+// statuses returns the non empty status strings of results
+func statuses(results map[string]Result) map[string]string {
+	if results == nil {
+		return nil
+	}
+
+	status := map[string]string{}
+	for name, res := range results {
+		if len(res.Status) > 0 {
+			status[name] = res.Status
+		}
+	}
+	return status
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+type bucket struct {
+	Depends
+	Arn string
+}
+
+func (b *bucket) UpdateResult(ctxt context.Context) (Result, error) {
+	b.Arn = "arn:bucket"
+	return Result{
+		Status:   "bucket ready",
+		Outputs:  map[string]interface{}{"Arn": b.Arn},
+		Action:   Created,
+		Warnings: []string{"versioning disabled"},
+	}, nil
+}
+
+func (b *bucket) Update(ctxt context.Context) (string, error) { panic("UpdateResult expected") }
+func (b *bucket) Delete(ctxt context.Context) error           { return nil }
+
+func TestSyncResults(t *testing.T) {
+	ctxt := context.Background()
+
+	resources := []Resource{
+		&bucket{Depends: Depends{Name: "mybkt"}},
+		MakeResource("mydep", []Dependency{{FromResource: "mybkt", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { return "deployed " + x.(*deployment).KinesisArn, nil }, func(x interface{}) error { return nil }),
+		MakeResource("mydyn", nil, &dynamo{ctxt: ctxt}, func(x interface{}) (string, error) { return "", nil }, func(x interface{}) error { return nil }),
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log, StateStore: NewMemoryStore(), SkipUnchanged: true})
+
+	results, err := lib.SyncResults(ctxt, resources, false)
+	if err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+
+	expected := map[string]Result{
+		"mybkt": {Status: "bucket ready", Outputs: map[string]interface{}{"Arn": "arn:bucket"}, Action: Created, Warnings: []string{"versioning disabled"}},
+		"mydep": {Status: "deployed arn:bucket", Action: Updated},
+		"mydyn": {Action: Updated},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Fatalf("expected %v, got %v", expected, results)
+	}
+
+	status, err := lib.Sync(ctxt, resources, false)
+	if err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+
+	if !reflect.DeepEqual(status, map[string]string{"mybkt": "bucket ready", "mydep": "deployed arn:bucket"}) {
+		t.Fatalf("unexpected status %v", status)
+	}
+
+	if results, _ = lib.SyncResults(ctxt, resources, false); results["mydep"].Action != Unchanged || results["mydep"].Status != "deployed arn:bucket" {
+		t.Fatalf("expected mydep to be unchanged, got %v", results["mydep"])
+	}
+}
+
+type statusOnly struct {
+	Resource
+}
+
+func TestSyncResultsDecorated(t *testing.T) {
+	ctxt := context.Background()
+
+	resources := []Resource{&bucket{Depends: Depends{Name: "mybkt"}}}
+
+	decorated := 0
+	lib := New(&Opts{CustomLogger: t.Log, Decorator: func(r Resource) Resource { decorated++; return &statusOnly{r} }})
+
+	results, err := lib.SyncResults(ctxt, resources, false)
+	if err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+
+	if results["mybkt"].Action != Created || decorated == 0 {
+		t.Fatalf("expected the decorator to run and UpdateResult of the undecorated resource, got %v, decorated %d", results, decorated)
+	}
+}
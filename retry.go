@@ -0,0 +1,73 @@
+package graph
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// RetryPolicy controls how Sync retries a Resource whose Update failed. Only
+// the failing Resource is retried, resources that already succeeded are not
+// updated again.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of Update calls, including the first one.
+	MaxAttempts int
+	// InitialDelay is the wait before the first retry.
+	InitialDelay time.Duration
+	// Multiplier grows the delay after every retry, values below 1 keep it constant.
+	Multiplier float64
+	// Jitter randomizes every delay by up to the given fraction, e.g. 0.2 for +/-20%.
+	Jitter float64
+	// Retryable reports whether an error is worth retrying, all errors are retried when nil.
+	Retryable func(error) bool
+}
+
+// Retrier may be implemented by a Resource to override the RetryPolicy set in Opts.
+type Retrier interface {
+	RetryPolicy() *RetryPolicy
+}
+
+// delay before the given retry, starting at 1
+func (p *RetryPolicy) delay(retry int) time.Duration {
+	d := float64(p.InitialDelay)
+	if p.Multiplier > 1 {
+		d *= math.Pow(p.Multiplier, float64(retry-1))
+	}
+	if p.Jitter > 0 {
+		d *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	return time.Duration(d)
+}
+
+// retry calls fn according to the RetryPolicy of r. Retries stop as soon as
+// the context is done, and the last error of fn is returned.
+func (lib *Lib) retry(ctxt context.Context, r Resource, fn func() error) error {
+	policy := lib.retryPolicy
+	if rr, ok := r.(Retrier); ok {
+		if p := rr.RetryPolicy(); p != nil {
+			policy = p
+		}
+	}
+
+	err := fn()
+	if policy == nil {
+		return err
+	}
+
+	for attempt := 1; err != nil && attempt < policy.MaxAttempts; attempt++ {
+		if policy.Retryable != nil && !policy.Retryable(err) {
+			break
+		}
+
+		lib.logger("retrying resource", r.ResourceName(), "attempt", attempt+1, "error", err)
+
+		if sleepWithContext(ctxt, policy.delay(attempt)) != nil {
+			break
+		}
+
+		err = fn()
+	}
+
+	return err
+}
@@ -0,0 +1,86 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var errNotReady = errors.New("not ready yet")
+
+type flaky struct {
+	Depends
+	failures int32
+	calls    int32
+	policy   *RetryPolicy
+}
+
+func (f *flaky) Update(ctxt context.Context) (string, error) {
+	if atomic.AddInt32(&f.calls, 1) <= f.failures {
+		return "", errNotReady
+	}
+	return "ready", nil
+}
+
+func (f *flaky) Delete(ctxt context.Context) error { return nil }
+
+func (f *flaky) RetryPolicy() *RetryPolicy { return f.policy }
+
+func TestSyncRetry(t *testing.T) {
+	ctxt := context.Background()
+
+	kin := &flaky{Depends: Depends{Name: "mykin"}, failures: 2}
+	dep := &flaky{Depends: Depends{Name: "mydep", Dependencies: []Dependency{{FromResource: "mykin"}}}}
+
+	lib := New(&Opts{CustomLogger: t.Log, RetryPolicy: &RetryPolicy{MaxAttempts: 3, InitialDelay: time.Millisecond, Multiplier: 2, Jitter: 0.5}})
+
+	status, err := lib.Sync(ctxt, []Resource{kin, dep}, false)
+	if err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+
+	if status["mykin"] != "ready" || kin.calls != 3 || dep.calls != 1 {
+		t.Fatalf("expected only mykin to be retried, status %v, calls mykin=%d mydep=%d", status, kin.calls, dep.calls)
+	}
+}
+
+func TestSyncRetryOverride(t *testing.T) {
+	ctxt := context.Background()
+
+	notRetryable := &RetryPolicy{MaxAttempts: 5, Retryable: func(err error) bool { return err != errNotReady }}
+	kin := &flaky{Depends: Depends{Name: "mykin"}, failures: 2, policy: notRetryable}
+
+	lib := New(&Opts{CustomLogger: t.Log, RetryPolicy: &RetryPolicy{MaxAttempts: 3}})
+
+	_, err := lib.Sync(ctxt, []Resource{kin}, false)
+	if err == nil {
+		t.Fatal("expected sync to fail")
+	}
+
+	if kin.calls != 1 {
+		t.Fatalf("expected the resource policy to prevent retries, calls=%d", kin.calls)
+	}
+}
+
+func TestSyncRetryCanceled(t *testing.T) {
+	ctxt, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	kin := &flaky{Depends: Depends{Name: "mykin"}, failures: 2}
+
+	lib := New(&Opts{CustomLogger: t.Log, RetryPolicy: &RetryPolicy{MaxAttempts: 3, InitialDelay: time.Hour}})
+
+	_, err := lib.Sync(ctxt, []Resource{kin}, false)
+
+	em, ok := err.(ErrorMapper)
+	if !ok {
+		t.Fatalf("expected an ErrorMapper, got %v", err)
+	}
+
+	var ce *CanceledError
+	if !errors.As(em.ErrorMap()["mykin"], &ce) || atomic.LoadInt32(&kin.calls) != 1 {
+		t.Fatalf("expected retries of mykin to stop with the context, got %v after %d calls", err, atomic.LoadInt32(&kin.calls))
+	}
+}
@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Rollbacker may be implemented by a Resource to undo its Update when a
+// transactional Sync fails. Like UpdateResult, it is looked up on the decorated
+// Resource first. Resources not implementing it are deleted instead.
+type Rollbacker interface {
+	Rollback(ctxt context.Context) error
+}
+
+// RollbackError is returned by a transactional Sync whose creation of resources
+// failed. The resources updated by the Sync have been rolled back, except for
+// the ones listed in Failures.
+type RollbackError struct {
+	// Err is the original failure.
+	Err error
+	// Failures holds the errors of resources that could not be rolled back,
+	// keyed by resource name.
+	Failures map[string]error
+}
+
+func (re *RollbackError) Error() string {
+	if len(re.Failures) == 0 {
+		return fmt.Sprintf("rolled back after failure: %v", re.Err)
+	}
+	return fmt.Sprintf("rollback failed: %v, after failure: %v", errorMap(re.Failures), re.Err)
+}
+
+// Unwrap returns the original failure
+func (re *RollbackError) Unwrap() error {
+	return re.Err
+}
+
+// ErrorMap returns the resource errors of the original failure
+func (re *RollbackError) ErrorMap() map[string]error {
+	if em, ok := re.Err.(ErrorMapper); ok {
+		return em.ErrorMap()
+	}
+	return nil
+}
+
+// detached keeps the values of a context, like SyncBag, without its deadline
+// and cancellation
+type detached struct {
+	context.Context
+}
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+
+// rollbackSync undoes the created resources in reverse order, and returns the
+// errors of resources that could not be rolled back. As the failure may be the
+// context being done, resources are rolled back under a detached context, each
+// one limited by its timeout.
+func (lib *Lib) rollbackSync(ctxt context.Context, resources []Resource, created []int) map[string]error {
+	errs := map[string]error{}
+	ctxt = detached{ctxt}
+
+	for k := len(created) - 1; k >= 0; k-- {
+		r := resources[created[k]]
+
+		lib.logger("rolling back resource", r.ResourceName())
+
+		_, err := lib.bounded(ctxt, r, func(ctxt context.Context) error {
+			d := lib.decorator(r)
+			rb, ok := d.(Rollbacker)
+			if !ok {
+				rb, ok = r.(Rollbacker)
+			}
+
+			var err error
+			if ok {
+				err = rb.Rollback(ctxt)
+			} else {
+				err = d.Delete(ctxt)
+			}
+			if err == nil && lib.store != nil {
+				err = lib.store.Remove(ctxt, r.ResourceName())
+			}
+			return err
+		})
+
+		if err != nil {
+			lib.logger("error rolling back resource", "resource", r, "error", err)
+			errs[r.ResourceName()] = err
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,115 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type rollbackTable struct {
+	Depends
+	undo func() error
+}
+
+func (rt *rollbackTable) Update(ctxt context.Context) (string, error) { return "created", nil }
+func (rt *rollbackTable) Delete(ctxt context.Context) error           { return errors.New("rollback expected") }
+func (rt *rollbackTable) Rollback(ctxt context.Context) error         { return rt.undo() }
+
+func TestSyncRollback(t *testing.T) {
+	ctxt := context.Background()
+
+	undone := []string{}
+	del := func(name string) func(interface{}) error {
+		return func(x interface{}) error { undone = append(undone, name); return nil }
+	}
+	ok := func(x interface{}) (string, error) { return "created", nil }
+	fail := errors.New("deployment failed")
+
+	tableFailure := errors.New("table is protected")
+
+	resources := []Resource{
+		MakeResource("mykin", nil, &kinesis{ctxt: ctxt}, ok, del("mykin")),
+		&rollbackTable{Depends: Depends{Name: "mydyn"}, undo: func() error { undone = append(undone, "mydyn"); return tableFailure }},
+		MakeResource("myalarm", []Dependency{{FromResource: "mykin"}}, &dynamo{ctxt: ctxt}, ok, del("myalarm")),
+		MakeResource("mydep", []Dependency{{FromResource: "myalarm"}, {FromResource: "mydyn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { return "", fail }, del("mydep")),
+	}
+
+	// the decorator hides Rollbacker, it is found on the undecorated resource
+	decorated := 0
+	decorator := func(r Resource) Resource { decorated++; return &statusOnly{r} }
+
+	lib := New(&Opts{CustomLogger: t.Log, MaxConcurrency: 1, Rollback: true, StateStore: NewMemoryStore(), Decorator: decorator})
+
+	_, err := lib.Sync(ctxt, resources, false)
+
+	var re *RollbackError
+	if !errors.As(err, &re) {
+		t.Fatalf("expected a RollbackError, got %v", err)
+	}
+
+	t.Logf("err = %v", err)
+
+	if !reflect.DeepEqual(undone, []string{"myalarm", "mydyn", "mykin"}) {
+		t.Fatalf("expected created resources to be rolled back in reverse order, got %v", undone)
+	}
+
+	if decorated != 2*len(resources)-1 {
+		t.Fatalf("expected updates and rollbacks to be decorated, got %d", decorated)
+	}
+
+	if re.ErrorMap()["mydep"] != fail || len(re.ErrorMap()) != 1 {
+		t.Fatalf("expected the original failure, got %v", re.ErrorMap())
+	}
+
+	if !reflect.DeepEqual(re.Failures, map[string]error{"mydyn": tableFailure}) {
+		t.Fatalf("expected mydyn rollback to fail, got %v", re.Failures)
+	}
+
+	if records, _ := lib.LoadState(ctxt); len(records) != 1 || records["mydyn"].Status != "created" {
+		t.Fatalf("expected only mydyn to remain recorded, got %v", records)
+	}
+}
+
+type contextTable struct {
+	Depends
+	undone bool
+}
+
+func (ct *contextTable) Update(ctxt context.Context) (string, error) { return "created", nil }
+
+func (ct *contextTable) Delete(ctxt context.Context) error {
+	if err := ctxt.Err(); err != nil {
+		return err
+	}
+	if ctxt.Value(SyncBag) == nil {
+		return errors.New("missing sync bag")
+	}
+	ct.undone = true
+	return nil
+}
+
+func TestSyncRollbackCanceled(t *testing.T) {
+	ctxt, cancel := context.WithCancel(context.WithValue(context.Background(), SyncBag, map[string]string{"namespace": "myns"}))
+	defer cancel()
+
+	table := &contextTable{Depends: Depends{Name: "mydyn"}}
+
+	resources := []Resource{
+		table,
+		MakeResource("mydep", []Dependency{{FromResource: "mydyn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { cancel(); return "", context.Canceled }, func(x interface{}) error { return nil }),
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log, Rollback: true})
+
+	_, err := lib.Sync(ctxt, resources, false)
+
+	var re *RollbackError
+	if !errors.As(err, &re) || len(re.Failures) != 0 {
+		t.Fatalf("expected a successful rollback, got %v", err)
+	}
+
+	if !table.undone {
+		t.Fatal("expected mydyn to be deleted after the context was canceled")
+	}
+}
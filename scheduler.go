@@ -0,0 +1,126 @@
+package graph
+
+import "context"
+
+// work describes the vertices processed by schedule
+type work struct {
+	g *graph
+	// less orders vertices that are ready at the same time, when nil they start
+	// in the order they became ready
+	less func(v, w int) bool
+	// continueOnError keeps running vertices that do not depend on a failure
+	continueOnError bool
+	// run processes a vertex
+	run func(v int) error
+	// skipped builds the error of a vertex depending on the failed vertex
+	skipped func(v, failed int) error
+	// canceled builds the error of a vertex not started as the context is done
+	canceled func(v int, err error) error
+}
+
+// scheduled is the outcome of running a single vertex
+type scheduled struct {
+	v   int
+	err error
+}
+
+// schedule runs every vertex of the DAG, starting a vertex as soon as all of its
+// parents have completed successfully. At most maxConcurrency vertices run at the
+// same time, vertices ready at the same time start in the order of less.
+//
+// On the first failure no new vertex is started unless continueOnError is set,
+// in which case every descendant of the failed vertex is recorded with the
+// skipped error, and the remaining vertices keep running. Once the context is
+// done no new vertex is started, and the vertices left are recorded with the
+// canceled error. The errors are returned keyed by vertex.
+//
+// Like sortBy, scheduling takes linear time on top of running the vertices when
+// less is nil, and O(V log V + E) time otherwise.
+func (lib *Lib) schedule(ctxt context.Context, w work) map[int]error {
+	g := w.g
+
+	// count parents that have not completed for every vertex
+	pending := make([]int, g.vertices())
+	for v := 0; v < g.vertices(); v++ {
+		for _, x := range g.adjascent(v) {
+			pending[x]++
+		}
+	}
+
+	roots := []int{}
+	for v := 0; v < g.vertices(); v++ {
+		if pending[v] == 0 {
+			roots = append(roots, v)
+		}
+	}
+	ready := newVertexQueue(roots, w.less)
+
+	errs := map[int]error{}
+	started := make([]bool, g.vertices())
+	done := make(chan scheduled, g.vertices())
+	running := 0
+	stopped := false
+
+	for {
+		if ctxt.Err() != nil {
+			stopped = true
+		}
+
+		for !stopped && ready.Len() > 0 && (lib.maxConcurrency <= 0 || running < lib.maxConcurrency) {
+			v := ready.pop()
+			started[v] = true
+			running++
+
+			lib.logger("executing ", v)
+			go func(v int) {
+				done <- scheduled{v, w.run(v)}
+			}(v)
+		}
+
+		if running == 0 {
+			break
+		}
+
+		out := <-done
+		running--
+
+		if out.err != nil {
+			errs[out.v] = out.err
+			if !w.continueOnError {
+				stopped = true
+				continue
+			}
+
+			// descendants of a failed vertex never become ready. The walk stops at
+			// vertices with an error, their descendants already have one.
+			stack := append([]int{}, g.adjascent(out.v)...)
+			for len(stack) > 0 {
+				x := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				if _, found := errs[x]; found {
+					continue
+				}
+				errs[x] = w.skipped(x, out.v)
+				stack = append(stack, g.adjascent(x)...)
+			}
+			continue
+		}
+
+		for _, x := range g.adjascent(out.v) {
+			pending[x]--
+			if pending[x] == 0 {
+				ready.push(x)
+			}
+		}
+	}
+
+	if ctxt.Err() != nil {
+		for v := 0; v < g.vertices(); v++ {
+			if _, found := errs[v]; !found && !started[v] {
+				errs[v] = w.canceled(v, ctxt.Err())
+			}
+		}
+	}
+
+	return errs
+}
@@ -0,0 +1,99 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestScheduleUnevenBranches(t *testing.T) {
+	// 0 is slow and only finishes once 2, which depends on 1, has run
+	g := newGraph(3)
+	g.addEdge(1, 2)
+
+	finished := make(chan struct{})
+
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	errs := lib.schedule(context.Background(), work{g: g, less: func(v, w int) bool { return v < w }, run: func(v int) error {
+		switch v {
+		case 0:
+			select {
+			case <-finished:
+				return nil
+			case <-time.After(5 * time.Second):
+				return errors.New("2 did not run while 0 was in progress")
+			}
+		case 2:
+			close(finished)
+		}
+		return nil
+	}})
+
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+}
+
+func TestScheduleMaxConcurrency(t *testing.T) {
+	g := newGraph(6)
+	g.addEdge(0, 5)
+	g.addEdge(1, 5)
+
+	var mux sync.Mutex
+	running, maxRunning := 0, 0
+
+	lib := New(&Opts{CustomLogger: t.Log, MaxConcurrency: 2})
+
+	errs := lib.schedule(context.Background(), work{g: g, less: func(v, w int) bool { return v < w }, run: func(v int) error {
+		mux.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mux.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mux.Lock()
+		running--
+		mux.Unlock()
+		return nil
+	}})
+
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors %v", errs)
+	}
+
+	if maxRunning != 2 {
+		t.Fatalf("expected at most 2 concurrent vertices, got %d", maxRunning)
+	}
+}
+
+func TestScheduleStopOnError(t *testing.T) {
+	g := newGraph(3)
+	g.addEdge(0, 1)
+	g.addEdge(1, 2)
+
+	ran := []int{}
+
+	lib := New(&Opts{CustomLogger: t.Log, MaxConcurrency: 1})
+
+	errs := lib.schedule(context.Background(), work{g: g, less: func(v, w int) bool { return v < w }, run: func(v int) error {
+		ran = append(ran, v)
+		if v == 1 {
+			return errors.New("failed")
+		}
+		return nil
+	}})
+
+	if len(errs) != 1 || errs[1] == nil {
+		t.Fatalf("expected only 1 to fail, got %v", errs)
+	}
+
+	if len(ran) != 2 {
+		t.Fatalf("expected 2 not to run, ran %v", ran)
+	}
+}
@@ -0,0 +1,289 @@
+package graph
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Record captures the outcome of the last successful Update of a Resource.
+type Record struct {
+	// Name of the Resource.
+	Name string `json:"name"`
+	// Status returned by Update.
+	Status string `json:"status"`
+	// Fields holds the values injected from dependencies, keyed by ToField.
+	Fields map[string]interface{} `json:"fields,omitempty"`
+	// Outputs holds the values read by dependent resources after Update, keyed
+	// by FromField.
+	Outputs map[string]interface{} `json:"outputs,omitempty"`
+	// Hash of the exported fields of the Resource, as passed to Update.
+	Hash string `json:"hash"`
+	// Timestamp of the Update.
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StateStore persists a Record of every synced Resource between runs.
+// Implementations must be safe for concurrent use.
+type StateStore interface {
+	// Load returns the stored records keyed by resource name.
+	Load(ctxt context.Context) (map[string]Record, error)
+	// Save stores a Record, replacing the previous one of the same name.
+	Save(ctxt context.Context, rec Record) error
+	// Remove forgets the Record of a deleted Resource.
+	Remove(ctxt context.Context, name string) error
+}
+
+type memoryStore struct {
+	mux     sync.Mutex
+	records map[string]Record
+}
+
+// NewMemoryStore creates a StateStore that keeps records for the lifetime of the process.
+func NewMemoryStore() StateStore {
+	return &memoryStore{records: map[string]Record{}}
+}
+
+func (ms *memoryStore) Load(ctxt context.Context) (map[string]Record, error) {
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+
+	records := make(map[string]Record, len(ms.records))
+	for name, rec := range ms.records {
+		records[name] = rec
+	}
+	return records, nil
+}
+
+func (ms *memoryStore) Save(ctxt context.Context, rec Record) error {
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+
+	ms.records[rec.Name] = rec
+	return nil
+}
+
+func (ms *memoryStore) Remove(ctxt context.Context, name string) error {
+	ms.mux.Lock()
+	defer ms.mux.Unlock()
+
+	delete(ms.records, name)
+	return nil
+}
+
+type fileStore struct {
+	mux  sync.Mutex
+	path string
+}
+
+// NewFileStore creates a StateStore that keeps records as JSON in the file at path.
+// A missing file is treated as an empty store.
+func NewFileStore(path string) StateStore {
+	return &fileStore{path: path}
+}
+
+func (fs *fileStore) Load(ctxt context.Context) (map[string]Record, error) {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+
+	return fs.read()
+}
+
+func (fs *fileStore) Save(ctxt context.Context, rec Record) error {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+
+	records, err := fs.read()
+	if err != nil {
+		return err
+	}
+
+	records[rec.Name] = rec
+	return fs.write(records)
+}
+
+func (fs *fileStore) Remove(ctxt context.Context, name string) error {
+	fs.mux.Lock()
+	defer fs.mux.Unlock()
+
+	records, err := fs.read()
+	if err != nil {
+		return err
+	}
+
+	delete(records, name)
+	return fs.write(records)
+}
+
+func (fs *fileStore) read() (map[string]Record, error) {
+	records := map[string]Record{}
+
+	data, err := ioutil.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return records, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("unable to parse state file %s: %v", fs.path, err)
+	}
+	return records, nil
+}
+
+// write replaces the state file through a rename, so that readers never see
+// a partially written file
+func (fs *fileStore) write(records map[string]Record) error {
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(fs.path), filepath.Base(fs.path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), fs.path)
+}
+
+// LoadState returns the records of the StateStore set in Opts, keyed by resource name.
+func (lib *Lib) LoadState(ctxt context.Context) (map[string]Record, error) {
+	if lib.store == nil {
+		return nil, fmt.Errorf("no StateStore configured")
+	}
+	return lib.store.Load(ctxt)
+}
+
+// record saves the state of a successfully updated Resource, along with the
+// output fields read by its dependents
+func (lib *Lib) record(ctxt context.Context, r Resource, status, hash string, outputs []string) error {
+	rec := Record{
+		Name:      r.ResourceName(),
+		Status:    status,
+		Hash:      hash,
+		Timestamp: time.Now(),
+	}
+
+	for _, dep := range declared(r) {
+		if len(dep.ToField) == 0 {
+			continue
+		}
+		if rec.Fields == nil {
+			rec.Fields = map[string]interface{}{}
+		}
+		v, err := getPath(backing(r), dep.ToField)
+		if err != nil {
+			return fmt.Errorf("unable to record %s of %s: %v", dep.ToField, r.ResourceName(), err)
+		}
+		rec.Fields[dep.ToField] = v.Interface()
+	}
+
+	for _, field := range outputs {
+		if rec.Outputs == nil {
+			rec.Outputs = map[string]interface{}{}
+		}
+		v, err := getPath(backing(r), field)
+		if err != nil {
+			return fmt.Errorf("unable to record %s of %s: %v", field, r.ResourceName(), err)
+		}
+		rec.Outputs[field] = v.Interface()
+	}
+
+	return lib.store.Save(ctxt, rec)
+}
+
+// restoreOutputs sets the output fields of a Resource whose Update is skipped
+func restoreOutputs(r Resource, rec Record) error {
+	for field, value := range rec.Outputs {
+		f, err := decodeField(r, field, value)
+		if err != nil {
+			return err
+		}
+
+		if err := setPath(backing(r), field, f); err != nil {
+			return fmt.Errorf("unable to restore field %s of %s: %v", field, r.ResourceName(), err)
+		}
+	}
+	return nil
+}
+
+// decodeField converts a recorded value to the type of a field of a Resource,
+// recorded values may have gone through JSON
+func decodeField(r Resource, field string, value interface{}) (reflect.Value, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	t, err := pathType(backingType(r), field)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("in %s Resource did not find recorded field %s", r.ResourceName(), field)
+	}
+
+	f := reflect.New(t)
+	if err := json.Unmarshal(data, f.Interface()); err != nil {
+		return reflect.Value{}, fmt.Errorf("unable to restore field %s of %s: %v", field, r.ResourceName(), err)
+	}
+	return f.Elem(), nil
+}
+
+// specHash computes a stable hash of the exported fields of the struct implementing
+// a Resource. Fields that cannot be serialized, like functions and channels, are ignored.
+func specHash(r Resource) (string, error) {
+	v := backing(r)
+	h := sha256.New()
+
+	// values other than structs are hashed whole
+	if v.Kind() != reflect.Struct {
+		var value interface{}
+		if v.IsValid() {
+			value = v.Interface()
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", fmt.Errorf("unable to hash %s: %v", r.ResourceName(), err)
+		}
+		h.Write(data)
+		return hex.EncodeToString(h.Sum(nil)), nil
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if len(field.PkgPath) > 0 {
+			continue
+		}
+
+		switch field.Type.Kind() {
+		case reflect.Func, reflect.Chan, reflect.UnsafePointer:
+			continue
+		}
+
+		data, err := json.Marshal(v.Field(i).Interface())
+		if err != nil {
+			return "", fmt.Errorf("unable to hash field %s of %s: %v", field.Name, r.ResourceName(), err)
+		}
+
+		fmt.Fprintf(h, "%s=%s;", field.Name, data)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
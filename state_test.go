@@ -0,0 +1,171 @@
+package graph
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileStore(t *testing.T) {
+	ctxt := context.Background()
+
+	store := NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+
+	records, err := store.Load(ctxt)
+	if err != nil || len(records) != 0 {
+		t.Fatalf("expected an empty store, got %v, err = %v", records, err)
+	}
+
+	if err := store.Save(ctxt, Record{Name: "mykin", Status: "created", Hash: "abc"}); err != nil {
+		t.Fatalf("unable to save %v", err)
+	}
+	if err := store.Save(ctxt, Record{Name: "mydep", Fields: map[string]interface{}{"KinesisArn": "hello123"}}); err != nil {
+		t.Fatalf("unable to save %v", err)
+	}
+	if err := store.Remove(ctxt, "mykin"); err != nil {
+		t.Fatalf("unable to remove %v", err)
+	}
+
+	records, err = store.Load(ctxt)
+	if err != nil {
+		t.Fatalf("unable to load %v", err)
+	}
+
+	if _, found := records["mykin"]; found || records["mydep"].Fields["KinesisArn"] != "hello123" {
+		t.Fatalf("unexpected records %v", records)
+	}
+}
+
+func TestSyncState(t *testing.T) {
+	ctxt := context.Background()
+
+	arn := "hello123"
+
+	kinesisResource := MakeResource("mykin", nil, &kinesis{ctxt, arn}, func(x interface{}) (string, error) { return "created", nil }, func(x interface{}) error { return nil })
+	deploymentResource := MakeResource("mydep1", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) { return "deployed", nil }, func(x interface{}) error { return nil })
+
+	resources := []Resource{kinesisResource, deploymentResource}
+
+	lib := New(&Opts{CustomLogger: t.Log, StateStore: NewMemoryStore()})
+
+	if _, err := lib.Sync(ctxt, resources, false); err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+
+	records, err := lib.LoadState(ctxt)
+	if err != nil {
+		t.Fatalf("unable to load state %v", err)
+	}
+
+	rec := records["mydep1"]
+	if rec.Status != "deployed" || rec.Fields["KinesisArn"] != arn || len(rec.Hash) == 0 || rec.Timestamp.IsZero() {
+		t.Fatalf("unexpected record %v", rec)
+	}
+
+	if _, err := lib.Sync(ctxt, resources, true); err != nil {
+		t.Fatalf("unable to delete %v", err)
+	}
+
+	if records, _ = lib.LoadState(ctxt); len(records) != 0 {
+		t.Fatalf("expected deleted resources to be removed, got %v", records)
+	}
+}
+
+func TestSpecHash(t *testing.T) {
+	ctxt := context.Background()
+
+	first := MakeResource("mydep1", nil, &deployment{ctxt: ctxt, KinesisArn: "a"}, nil, nil)
+	second := MakeResource("mydep1", nil, &deployment{ctxt: context.TODO(), KinesisArn: "a"}, nil, nil)
+	third := MakeResource("mydep1", nil, &deployment{ctxt: ctxt, KinesisArn: "b"}, nil, nil)
+
+	h1, _ := specHash(first)
+	h2, _ := specHash(second)
+	h3, _ := specHash(third)
+
+	if h1 != h2 {
+		t.Fatal("expected unexported fields not to change the hash")
+	}
+	if h1 == h3 {
+		t.Fatal("expected exported fields to change the hash")
+	}
+
+	name, other := "events", "table"
+	h4, err := specHash(MakeResource("mystr", nil, &name, nil, nil))
+	if err != nil {
+		t.Fatalf("unable to hash a string %v", err)
+	}
+	if h5, _ := specHash(MakeResource("mystr", nil, &other, nil, nil)); h4 == h5 {
+		t.Fatal("expected the value of a string to change the hash")
+	}
+
+	ch := make(chan int)
+	if _, err := specHash(MakeResource("mychan", nil, &ch, nil, nil)); err == nil {
+		t.Fatal("expected a value that cannot be serialized to fail")
+	}
+}
+
+func TestSyncStateNonStruct(t *testing.T) {
+	ctxt := context.Background()
+
+	name := "events"
+	resources := []Resource{MakeResource("mystr", nil, &name, func(x interface{}) (string, error) { return *x.(*string), nil }, func(x interface{}) error { return nil })}
+
+	lib := New(&Opts{CustomLogger: t.Log, StateStore: NewMemoryStore()})
+
+	if status, err := lib.Sync(ctxt, resources, false); err != nil || status["mystr"] != name {
+		t.Fatalf("unexpected status %v, err = %v", status, err)
+	}
+}
+
+func TestSyncSkipUnchanged(t *testing.T) {
+	ctxt := context.Background()
+
+	calls := map[string]int{}
+	build := func(kinesisArn string) []Resource {
+		kinesisResource := MakeResource("mykin", nil, &kinesis{ctxt: ctxt, Arn: kinesisArn}, func(x interface{}) (string, error) {
+			calls["mykin"]++
+			k := x.(*kinesis)
+			if len(k.Arn) == 0 {
+				k.Arn = "hello123"
+			}
+			return "created " + k.Arn, nil
+		}, func(x interface{}) error { return nil })
+		deploymentResource := MakeResource("mydep1", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, func(x interface{}) (string, error) {
+			calls["mydep1"]++
+			return "reading " + x.(*deployment).KinesisArn, nil
+		}, func(x interface{}) error { return nil })
+		return []Resource{kinesisResource, deploymentResource}
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log, StateStore: NewMemoryStore(), SkipUnchanged: true})
+
+	if _, err := lib.Sync(ctxt, build(""), false); err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+
+	status, err := lib.Sync(ctxt, build(""), false)
+	if err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+
+	if calls["mykin"] != 1 || calls["mydep1"] != 1 {
+		t.Fatalf("expected unchanged resources to be skipped, calls %v", calls)
+	}
+
+	if status["mydep1"] != "reading hello123" {
+		t.Fatalf("expected the recorded status, got %v", status)
+	}
+
+	if _, err := lib.Sync(ctxt, build("other"), false); err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+
+	if calls["mykin"] != 2 || calls["mydep1"] != 2 {
+		t.Fatalf("expected changed resources to be updated, calls %v", calls)
+	}
+
+	lib = New(&Opts{CustomLogger: t.Log, SkipUnchanged: true})
+	if _, err := lib.Sync(ctxt, build(""), false); err == nil || calls["mykin"] != 2 {
+		t.Fatalf("expected SkipUnchanged without a StateStore to fail, got %v, calls %v", err, calls)
+	}
+}
@@ -0,0 +1,82 @@
+package graph
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// tagged holds the dependencies declared by the struct tags of a type
+type tagged struct {
+	deps []Dependency
+	err  error
+}
+
+// tagCache maps the types of resource structs to their tagged dependencies
+var tagCache sync.Map
+
+// declared returns the dependencies of a Resource, the ones of ResourceDependencies
+// followed by the ones declared with struct tags. Tag errors are reported by check.
+func declared(r Resource) []Dependency {
+	deps, _ := tagDependencies(r)
+	if len(deps) == 0 {
+		return r.ResourceDependencies()
+	}
+	return append(append([]Dependency{}, r.ResourceDependencies()...), deps...)
+}
+
+// tagDependencies parses the graph struct tags of the struct implementing a Resource.
+// A field tagged with `graph:"from=mykin.Arn"` receives the Arn field of mykin,
+// a kind option sets the kind of the dependency as in `graph:"from=mykin.Arn,kind=soft"`.
+// As tags always inject, the order only kind is rejected.
+func tagDependencies(r Resource) ([]Dependency, error) {
+	// only a pointer to a struct can be injected into
+	v := backing(r)
+	if v.Kind() != reflect.Struct || !v.CanAddr() {
+		return nil, nil
+	}
+
+	if t, found := tagCache.Load(v.Type()); found {
+		return t.(tagged).deps, t.(tagged).err
+	}
+
+	deps, err := parseTags(v.Type())
+	tagCache.Store(v.Type(), tagged{deps, err})
+	return deps, err
+}
+
+func parseTags(t reflect.Type) ([]Dependency, error) {
+	deps := []Dependency{}
+
+	for _, f := range reflect.VisibleFields(t) {
+		tag, ok := f.Tag.Lookup("graph")
+		if !ok || f.Anonymous {
+			continue
+		}
+
+		dep := Dependency{ToField: f.Name}
+		for _, option := range strings.Split(tag, ",") {
+			key, value, _ := strings.Cut(option, "=")
+			switch strings.TrimSpace(key) {
+			case "from":
+				dep.FromResource, dep.FromField, _ = strings.Cut(strings.TrimSpace(value), ".")
+			case "kind":
+				dep.Kind = DependencyKind(strings.TrimSpace(value))
+			default:
+				return nil, fmt.Errorf("unknown option %q in graph tag of field %s", option, f.Name)
+			}
+		}
+
+		if len(dep.FromResource) == 0 || len(dep.FromField) == 0 {
+			return nil, fmt.Errorf("graph tag of field %s must be from=resource.field", f.Name)
+		}
+		if dep.Kind == OrderOnly {
+			return nil, fmt.Errorf("graph tag of field %s injects a value, it cannot be of kind %s, use ResourceDependencies for ordering", f.Name, OrderOnly)
+		}
+
+		deps = append(deps, dep)
+	}
+
+	return deps, nil
+}
@@ -0,0 +1,125 @@
+package graph
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type taggedDeployment struct {
+	Depends
+	KinesisArn string `graph:"from=mykin.Arn"`
+	Port       int64  `graph:"from=mysvc.Status.Ports[0].Number, kind=soft"`
+}
+
+func (td *taggedDeployment) Update(ctxt context.Context) (string, error) {
+	return td.KinesisArn, nil
+}
+
+func (td *taggedDeployment) Delete(ctxt context.Context) error { return nil }
+
+type badTag struct {
+	Depends
+	KinesisArn string `graph:"from=mykin"`
+}
+
+func (bt *badTag) Update(ctxt context.Context) (string, error) { return "", nil }
+func (bt *badTag) Delete(ctxt context.Context) error           { return nil }
+
+type orderTag struct {
+	Depends
+	KinesisArn string `graph:"from=mykin.Arn,kind=order"`
+}
+
+func (ot *orderTag) Update(ctxt context.Context) (string, error) { return "", nil }
+func (ot *orderTag) Delete(ctxt context.Context) error           { return nil }
+
+func TestTagDependencies(t *testing.T) {
+	td := &taggedDeployment{Depends: Depends{Name: "mydep", Dependencies: []Dependency{{FromResource: "mydyn", Kind: OrderOnly}}}}
+
+	expected := []Dependency{
+		{FromResource: "mydyn", Kind: OrderOnly},
+		{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"},
+		{FromResource: "mysvc", FromField: "Status.Ports[0].Number", ToField: "Port", Kind: Soft},
+	}
+	if deps := declared(td); !reflect.DeepEqual(deps, expected) {
+		t.Fatalf("expected %v, got %v", expected, deps)
+	}
+
+	if _, err := tagDependencies(&badTag{}); err == nil || !strings.Contains(err.Error(), "from=resource.field") {
+		t.Fatalf("expected an invalid tag, got %v", err)
+	}
+
+	if _, err := tagDependencies(&orderTag{}); err == nil || !strings.Contains(err.Error(), "cannot be of kind order") {
+		t.Fatalf("expected an order only tag to be rejected, got %v", err)
+	}
+}
+
+func TestSyncTags(t *testing.T) {
+	ctxt := context.Background()
+
+	ok := func(x interface{}) (string, error) { return "", nil }
+	del := func(x interface{}) error { return nil }
+
+	td := &taggedDeployment{Depends: Depends{Name: "mydep"}}
+
+	resources := []Resource{
+		td,
+		MakeResource("mykin", nil, &kinesis{ctxt: ctxt, Arn: "hello123"}, ok, del),
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	status, err := lib.Sync(ctxt, resources, false)
+	if err != nil {
+		t.Fatalf("unable to sync %v", err)
+	}
+	if status["mydep"] != "hello123" {
+		t.Fatalf("unexpected status %v", status)
+	}
+
+	resources[1] = MakeResource("mykin", nil, &dynamo{ctxt: ctxt}, ok, del)
+	if _, err := lib.Sync(ctxt, resources, false); err == nil || !strings.Contains(err.Error(), "did not find field Arn") {
+		t.Fatalf("expected tagged dependency to be checked, got %v", err)
+	}
+
+	resources = []Resource{&badTag{Depends: Depends{Name: "mydep"}}}
+	if _, err := lib.Sync(ctxt, resources, false); err == nil {
+		t.Fatal("expected invalid tag to be rejected")
+	}
+}
+
+type zzval struct {
+	Arn string `graph:"from=mykin.Arn"`
+}
+
+func TestSyncUntaggableBacking(t *testing.T) {
+	ctxt := context.Background()
+
+	ok := func(x interface{}) (string, error) { return "done", nil }
+	del := func(x interface{}) error { return nil }
+
+	for name, uDef := range map[string]interface{}{"nil": nil, "value": zzval{}} {
+		resources := []Resource{MakeResource("a", nil, uDef, ok, del)}
+
+		for _, opts := range []*Opts{{CustomLogger: t.Log}, {CustomLogger: t.Log, StateStore: NewMemoryStore()}} {
+			status, err := New(opts).Sync(ctxt, resources, false)
+			if err != nil || status["a"] != "done" {
+				t.Fatalf("expected a %s uDef to sync, got %v, err = %v", name, status, err)
+			}
+		}
+
+		if deps, err := tagDependencies(resources[0]); len(deps) != 0 || err != nil {
+			t.Fatalf("expected no tags for a %s uDef, got %v, err = %v", name, deps, err)
+		}
+	}
+
+	resources := []Resource{
+		MakeResource("mykin", nil, &kinesis{ctxt: ctxt}, ok, del),
+		MakeResource("a", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "Arn"}}, nil, ok, del),
+	}
+	if _, err := New(&Opts{CustomLogger: t.Log}).Sync(ctxt, resources, false); err == nil || !strings.Contains(err.Error(), "there is no struct") {
+		t.Fatalf("expected injecting into a nil uDef to fail, got %v", err)
+	}
+}
@@ -0,0 +1,88 @@
+package graph
+
+import (
+	"context"
+	"fmt"
+)
+
+// SyncTargets works like Sync, but only processes the named target resources along
+// with the resources they depend on, or when toDelete is set, the resources
+// depending on them. Other resources of the slice are left untouched.
+func (lib *Lib) SyncTargets(ctxt context.Context, resources []Resource, targets []string, toDelete bool) (map[string]string, error) {
+	g, err := prepare(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	subset, sub, err := closure(resources, g, targets, toDelete)
+	if err != nil {
+		return nil, err
+	}
+
+	lib.logger("syncing targets", targets, "with", len(subset), "resources")
+
+	results, err := lib.sync(ctxt, subset, sub, toDelete)
+	return statuses(results), err
+}
+
+// closure selects the targets along with their ancestors, or descendants when
+// toDelete is set, and returns them in slice order with their DAG
+func closure(resources []Resource, g *graph, targets []string, toDelete bool) ([]Resource, *graph, error) {
+	index := indexes(resources)
+
+	sources := []int{}
+	for _, name := range targets {
+		i, found := index[name]
+		if !found {
+			return nil, nil, fmt.Errorf("target resource %s doesn't exist", name)
+		}
+		sources = append(sources, i)
+	}
+
+	// edges point from a resource to its dependents
+	walk := g
+	if !toDelete {
+		walk = g.transpose()
+	}
+
+	sub, kept := g.induced(reachable(walk, sources...))
+
+	subset := make([]Resource, len(kept))
+	for i, v := range kept {
+		subset[i] = resources[v]
+	}
+
+	return subset, sub, nil
+}
+
+// DeleteCascade deletes the named resource after every resource that transitively
+// depends on it, dependents being deleted in reverse order of dependencies. The
+// result of every Resource involved is returned keyed by name, a nil error meaning
+// the Resource was deleted. Failures are also returned as an ErrorMapper.
+func (lib *Lib) DeleteCascade(ctxt context.Context, resources []Resource, name string) (map[string]error, error) {
+	g, err := prepare(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	subset, sub, err := closure(resources, g, []string{name}, true)
+	if err != nil {
+		return nil, err
+	}
+
+	lib.logger("cascading delete of", name, "with", len(subset), "resources")
+
+	err = lib.deleteSync(ctxt, subset, sub)
+
+	results := map[string]error{}
+	for _, r := range subset {
+		results[r.ResourceName()] = nil
+	}
+	if em, ok := err.(ErrorMapper); ok {
+		for n, e := range em.ErrorMap() {
+			results[n] = e
+		}
+	}
+
+	return results, err
+}
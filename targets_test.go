@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// recorder tracks the resources that were updated or deleted
+type recorder struct {
+	mux     sync.Mutex
+	updated []string
+	deleted []string
+}
+
+func (rec *recorder) resource(name string, deps []Dependency, uDef interface{}) Resource {
+	return MakeResource(name, deps, uDef, func(x interface{}) (string, error) {
+		rec.mux.Lock()
+		defer rec.mux.Unlock()
+		rec.updated = append(rec.updated, name)
+		return name + " updated", nil
+	}, func(x interface{}) error {
+		rec.mux.Lock()
+		defer rec.mux.Unlock()
+		rec.deleted = append(rec.deleted, name)
+		return nil
+	})
+}
+
+func (rec *recorder) stacks(ctxt context.Context) []Resource {
+	return []Resource{
+		rec.resource("mykin", nil, &kinesis{ctxt: ctxt, Arn: "hello123"}),
+		rec.resource("mydyn", nil, &dynamo{ctxt: ctxt}),
+		rec.resource("mydep", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}),
+		rec.resource("mydep2", []Dependency{{FromResource: "mydyn"}}, &deployment{ctxt: ctxt}),
+		rec.resource("myapi", []Dependency{{FromResource: "mydep", FromField: "KinesisArn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}),
+	}
+}
+
+func TestSyncTargets(t *testing.T) {
+	ctxt := context.Background()
+
+	rec := &recorder{}
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	status, err := lib.SyncTargets(ctxt, rec.stacks(ctxt), []string{"mydep"}, false)
+	if err != nil {
+		t.Fatalf("unable to sync targets %v", err)
+	}
+
+	if len(rec.updated) != 2 || rec.updated[0] != "mykin" || rec.updated[1] != "mydep" || len(status) != 2 {
+		t.Fatalf("expected mydep and its ancestors only, updated %v", rec.updated)
+	}
+
+	if _, err := lib.SyncTargets(ctxt, rec.stacks(ctxt), []string{"mykin", "mydyn"}, true); err != nil {
+		t.Fatalf("unable to delete targets %v", err)
+	}
+
+	if len(rec.deleted) != 5 {
+		t.Fatalf("expected every descendant to be deleted, deleted %v", rec.deleted)
+	}
+
+	rec.deleted = nil
+	if _, err := lib.SyncTargets(ctxt, rec.stacks(ctxt), []string{"mydep"}, true); err != nil {
+		t.Fatalf("unable to delete targets %v", err)
+	}
+
+	if len(rec.deleted) != 2 || rec.deleted[0] != "myapi" || rec.deleted[1] != "mydep" {
+		t.Fatalf("expected mydep and its descendants only, deleted %v", rec.deleted)
+	}
+
+	if _, err := lib.SyncTargets(ctxt, rec.stacks(ctxt), []string{"nope"}, false); err == nil {
+		t.Fatal("expected unknown targets to fail")
+	}
+}
+
+func TestDeleteCascade(t *testing.T) {
+	ctxt := context.Background()
+
+	rec := &recorder{}
+	lib := New(&Opts{CustomLogger: t.Log})
+
+	results, err := lib.DeleteCascade(ctxt, rec.stacks(ctxt), "mykin")
+	if err != nil {
+		t.Fatalf("unable to delete %v", err)
+	}
+
+	if !reflect.DeepEqual(rec.deleted, []string{"myapi", "mydep", "mykin"}) {
+		t.Fatalf("expected dependents to be deleted before mykin, deleted %v", rec.deleted)
+	}
+
+	if len(results) != 3 || results["mykin"] != nil {
+		t.Fatalf("unexpected results %v", results)
+	}
+
+	stuck := errors.New("deployment stuck")
+	resources := rec.stacks(ctxt)
+	resources[2] = MakeResource("mydep", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, nil, func(x interface{}) error { return stuck })
+
+	results, err = lib.DeleteCascade(ctxt, resources, "mykin")
+	if _, ok := err.(ErrorMapper); !ok {
+		t.Fatalf("expected an ErrorMapper, got %v", err)
+	}
+
+	if results["myapi"] != nil || results["mydep"] != stuck {
+		t.Fatalf("unexpected results %v", results)
+	}
+
+	if se, ok := results["mykin"].(*SkippedError); !ok || se.Ancestor != "mydep" {
+		t.Fatalf("expected mykin to be skipped, got %v", results["mykin"])
+	}
+}
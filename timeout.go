@@ -0,0 +1,58 @@
+package graph
+
+import (
+	"context"
+	"time"
+)
+
+// Timeouter may be implemented by a Resource to override the Timeout set in Opts.
+type Timeouter interface {
+	ResourceTimeout() time.Duration
+}
+
+// bounded calls fn with a context limited by the timeout of r. When the timeout
+// expires, or the context is done, fn is abandoned and a TimeoutError or a
+// CanceledError is returned. The returned flag reports whether fn completed.
+func (lib *Lib) bounded(ctxt context.Context, r Resource, fn func(ctxt context.Context) error) (bool, error) {
+	timeout := lib.timeout
+	if t, ok := r.(Timeouter); ok {
+		if d := t.ResourceTimeout(); d > 0 {
+			timeout = d
+		}
+	}
+
+	bounded, cancel := ctxt, context.CancelFunc(func() {})
+	if timeout > 0 {
+		bounded, cancel = context.WithTimeout(ctxt, timeout)
+	}
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn(bounded)
+	}()
+
+	// interrupted reports why fn did not succeed in time
+	interrupted := func() error {
+		if ctxt.Err() != nil {
+			return &CanceledError{Resource: r.ResourceName(), Err: ctxt.Err()}
+		}
+		return &TimeoutError{Resource: r.ResourceName(), Timeout: timeout}
+	}
+
+	select {
+	case err := <-done:
+		if err != nil && bounded.Err() != nil {
+			return true, interrupted()
+		}
+		return true, err
+	case <-bounded.Done():
+		select {
+		case <-done:
+			return true, interrupted()
+		default:
+			lib.logger("abandoning resource", r.ResourceName(), "error", bounded.Err())
+			return false, interrupted()
+		}
+	}
+}
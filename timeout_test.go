@@ -0,0 +1,113 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type hung struct {
+	Depends
+	timeout time.Duration
+}
+
+func (h *hung) Update(ctxt context.Context) (string, error) {
+	select {}
+}
+
+func (h *hung) Delete(ctxt context.Context) error {
+	<-ctxt.Done()
+	return ctxt.Err()
+}
+
+func (h *hung) ResourceTimeout() time.Duration { return h.timeout }
+
+func TestSyncTimeout(t *testing.T) {
+	ctxt := context.Background()
+
+	resources := []Resource{
+		&hung{Depends: Depends{Name: "mykin"}},
+		&hung{Depends: Depends{Name: "mydyn"}, timeout: 5 * time.Millisecond},
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log, Timeout: 10 * time.Millisecond, ContinueOnError: true})
+
+	_, err := lib.Sync(ctxt, resources, false)
+
+	em, ok := err.(ErrorMapper)
+	if !ok {
+		t.Fatalf("expected an ErrorMapper, got %v", err)
+	}
+
+	for name, timeout := range map[string]time.Duration{"mykin": 10 * time.Millisecond, "mydyn": 5 * time.Millisecond} {
+		var te *TimeoutError
+		if !errors.As(em.ErrorMap()[name], &te) || te.Timeout != timeout {
+			t.Fatalf("expected %s to time out after %v, got %v", name, timeout, em.ErrorMap()[name])
+		}
+	}
+
+	_, err = lib.Sync(ctxt, resources, true)
+	if em, ok = err.(ErrorMapper); !ok || !errors.Is(em.ErrorMap()["mykin"], context.DeadlineExceeded) {
+		t.Fatalf("expected delete to time out, got %v", err)
+	}
+}
+
+func TestSyncCanceled(t *testing.T) {
+	ctxt, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ok := func(x interface{}) (string, error) { return "", nil }
+	del := func(x interface{}) error { return nil }
+
+	resources := []Resource{
+		MakeResource("mykin", nil, &kinesis{ctxt: ctxt}, func(x interface{}) (string, error) { cancel(); return "", nil }, del),
+		MakeResource("mydep", []Dependency{{FromResource: "mykin", FromField: "Arn", ToField: "KinesisArn"}}, &deployment{ctxt: ctxt}, ok, del),
+		MakeResource("mydyn", nil, &dynamo{ctxt: ctxt}, ok, del),
+	}
+
+	lib := New(&Opts{CustomLogger: t.Log, MaxConcurrency: 1})
+
+	_, err := lib.Sync(ctxt, resources, false)
+
+	em, isMapper := err.(ErrorMapper)
+	if !isMapper {
+		t.Fatalf("expected an ErrorMapper, got %v", err)
+	}
+
+	for _, name := range []string{"mydep", "mydyn"} {
+		var ce *CanceledError
+		if !errors.As(em.ErrorMap()[name], &ce) || ce.Err != context.Canceled {
+			t.Fatalf("expected %s not to start, got %v", name, em.ErrorMap()[name])
+		}
+	}
+}
+
+func TestSyncTimeoutNotRecorded(t *testing.T) {
+	ctxt := context.Background()
+
+	finished := make(chan struct{})
+	slow := func(x interface{}) (string, error) {
+		defer close(finished)
+		time.Sleep(50 * time.Millisecond)
+		return "created", nil
+	}
+
+	resources := []Resource{MakeResource("mykin", nil, &kinesis{ctxt: ctxt}, slow, func(x interface{}) error { return nil })}
+
+	lib := New(&Opts{CustomLogger: t.Log, Timeout: 10 * time.Millisecond, StateStore: NewMemoryStore()})
+
+	_, err := lib.Sync(ctxt, resources, false)
+
+	var te *TimeoutError
+	if !errors.As(err.(ErrorMapper).ErrorMap()["mykin"], &te) {
+		t.Fatalf("expected mykin to time out, got %v", err)
+	}
+
+	<-finished
+	time.Sleep(10 * time.Millisecond)
+
+	if records, _ := lib.LoadState(ctxt); len(records) != 0 {
+		t.Fatalf("expected an abandoned resource not to be recorded, got %v", records)
+	}
+}
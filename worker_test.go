@@ -2,6 +2,7 @@ package graph
 
 import (
 	"errors"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -15,7 +16,7 @@ type sleeper struct {
 func (s *sleeper) run() {
 	if s.id == 3 {
 		s.t.Logf("Worker %d throwing error", s.id)
-		s.tellme <- errors.New("issue in " + string(s.id))
+		s.tellme <- errors.New("issue in " + strconv.Itoa(s.id))
 	}
 	time.Sleep(1 * time.Millisecond)
 	s.t.Logf("Worker %v done successfully", s.id)